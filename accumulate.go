@@ -0,0 +1,19 @@
+package workers
+
+// Accumulate combines the results of workers, starting with init, on a single goroutine,
+// so combine needs no synchronization. The returned future is resolved to the accumulated
+// value once the results channel is closed. The results channel must not be read elsewhere.
+func Accumulate[R, A interface{}](w Workers[R], init A, combine func(A, R) A) *Future[A] {
+	future := newFuture[A]()
+
+	go func() {
+		acc := init
+		for r := range w.GetResults() {
+			acc = combine(acc, r)
+		}
+
+		future.resolve(acc)
+	}()
+
+	return future
+}
@@ -0,0 +1,63 @@
+package workers
+
+import (
+	"context"
+	"sync"
+)
+
+// fifoMutex is a mutex which is acquired in the order of LockContext calls.
+type fifoMutex struct {
+	mu      sync.Mutex
+	locked  bool
+	waiters []chan struct{}
+}
+
+// LockContext acquires the lock, waiting until it is passed by Unlock. It stops waiting once ctx is done,
+// returning ctx.Err().
+func (m *fifoMutex) LockContext(ctx context.Context) error {
+	m.mu.Lock()
+
+	if !m.locked {
+		m.locked = true
+		m.mu.Unlock()
+		return nil
+	}
+
+	ch := make(chan struct{})
+	m.waiters = append(m.waiters, ch)
+	m.mu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+	}
+
+	m.mu.Lock()
+	for i, waiter := range m.waiters {
+		if waiter == ch {
+			m.waiters = append(m.waiters[:i], m.waiters[i+1:]...)
+			m.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+	m.mu.Unlock()
+
+	// The lock has been passed to this caller meanwhile.
+	m.Unlock()
+	return ctx.Err()
+}
+
+// Unlock passes the lock to the longest waiting Lock caller, if any.
+func (m *fifoMutex) Unlock() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.waiters) == 0 {
+		m.locked = false
+		return
+	}
+
+	close(m.waiters[0])
+	m.waiters = m.waiters[1:]
+}
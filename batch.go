@@ -0,0 +1,103 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// BatchStream creates and starts workers executing fn for batches of up to k items received from in.
+// A batch is added as a task once it has k items, once interval, if positive, has elapsed
+// since its first item was received, or once in is closed. Workers are closed after in is closed
+// and the last batch is added, or when ctx is cancelled. A batch which cannot be added is reported
+// to the errors channel with an error wrapping ErrNotStarted.
+func BatchStream[T, R interface{}](
+	ctx context.Context,
+	in <-chan T,
+	k int,
+	interval time.Duration,
+	fn func(context.Context, []T) (R, error),
+	config *Config,
+) (Workers[R], error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("%w: batch size must be positive", ErrInvalidConfig)
+	}
+
+	w, err := New[R](ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	w.Start(ctx)
+
+	reporter := w.(interface{ reportError(error) })
+
+	go func() {
+		defer w.Close()
+
+		batch := make([]T, 0, k)
+		var (
+			timer *time.Timer
+			flush <-chan time.Time
+		)
+
+		// add adds the batch as a task, reporting it if it cannot be added.
+		add := func(cause error) bool {
+			if timer != nil {
+				timer.Stop()
+				timer, flush = nil, nil
+			}
+
+			if len(batch) == 0 {
+				return true
+			}
+
+			items := batch
+			batch = make([]T, 0, k)
+
+			if cause == nil {
+				cause = w.AddTask(func(ctx context.Context) (R, error) {
+					return fn(ctx, items)
+				})
+			}
+
+			if cause != nil {
+				reporter.reportError(fmt.Errorf("%w: batch of %d items: %w", ErrNotStarted, len(items), cause))
+				return false
+			}
+
+			return true
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				add(ctx.Err())
+				return
+
+			case <-flush:
+				timer, flush = nil, nil
+				if !add(nil) {
+					return
+				}
+
+			case item, ok := <-in:
+				if !ok {
+					add(nil)
+					return
+				}
+
+				batch = append(batch, item)
+				if len(batch) == 1 && interval > 0 {
+					timer = time.NewTimer(interval)
+					flush = timer.C
+				}
+
+				if len(batch) == k && !add(nil) {
+					return
+				}
+			}
+		}
+	}()
+
+	return w, nil
+}
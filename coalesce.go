@@ -0,0 +1,46 @@
+package workers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// coalescedError is an error repeated within ErrorCoalesceWindow.
+type coalescedError struct {
+	err      error
+	count    int
+	deadline time.Time
+}
+
+func (e *coalescedError) Error() string {
+	return fmt.Sprintf("%v (repeated %d times)", e.err, e.count)
+}
+
+func (e *coalescedError) Unwrap() error {
+	return e.err
+}
+
+// coalesced returns the error to publish: the original one if it has not been repeated.
+func (e *coalescedError) coalesced() error {
+	if e.count == 1 {
+		return e.err
+	}
+
+	return e
+}
+
+// ExtractRepeatCount returns the number of identical errors coalesced into err with ErrorCoalesceWindow.
+// It returns one for other errors, and zero for nil.
+func ExtractRepeatCount(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var c *coalescedError
+	if errors.As(err, &c) {
+		return c.count
+	}
+
+	return 1
+}
@@ -0,0 +1,28 @@
+package workers
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// writeEncoded encodes the result with ResultEncoder and writes it to EncodedOutput
+// as a frame prefixed with the big-endian uint32 length of the encoded result.
+func (w *workers[R]) writeEncoded(result R) error {
+	data, err := w.encode(result)
+	if err != nil {
+		return fmt.Errorf("encoding result: %w", err)
+	}
+
+	frame := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data)))
+	copy(frame[4:], data)
+
+	w.encodedMu.Lock()
+	defer w.encodedMu.Unlock()
+
+	if _, err = w.config.EncodedOutput.Write(frame); err != nil {
+		return fmt.Errorf("writing encoded result: %w", err)
+	}
+
+	return nil
+}
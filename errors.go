@@ -0,0 +1,57 @@
+package workers
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidConfig is returned by New if the passed configuration cannot be used,
+	// and on using a feature which is not configured.
+	ErrInvalidConfig = errors.New("invalid config")
+
+	// ErrInvalidState is returned on adding a task to workers which are closed or stopped.
+	ErrInvalidState = errors.New("invalid state")
+
+	// ErrAlreadyStarted is returned by StartErr if workers have already been started.
+	ErrAlreadyStarted = errors.New("already started")
+
+	// ErrQueueFull is returned on adding a task if MaxQueueDepth is reached.
+	ErrQueueFull = errors.New("queue full")
+
+	// ErrErrorsDropped is returned by CloseErr if some task errors have not been sent to the errors channel.
+	ErrErrorsDropped = errors.New("errors dropped")
+
+	// ErrNotStarted is set for tasks which have not been executed.
+	ErrNotStarted = errors.New("task not started")
+
+	// ErrTaskPanicked is wrapped by errors reported for panicking tasks.
+	ErrTaskPanicked = errors.New("task execution panicked")
+
+	// ErrTaskCancelled is returned for a task cancelled before or during its execution.
+	ErrTaskCancelled = errors.New("task cancelled")
+)
+
+// PanicError is reported for a panicking task. It wraps ErrTaskPanicked.
+type PanicError struct {
+	value interface{}
+	stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrTaskPanicked, e.value)
+}
+
+func (e *PanicError) Unwrap() error {
+	return ErrTaskPanicked
+}
+
+// Value returns the value passed to panic.
+func (e *PanicError) Value() interface{} {
+	return e.value
+}
+
+// Stack returns the stack trace of the panicking goroutine.
+func (e *PanicError) Stack() []byte {
+	return e.stack
+}
@@ -0,0 +1,118 @@
+package workers
+
+import "time"
+
+const errorsBufferSize = 1024
+
+// publishErrors forwards published errors to the errors channel, unless SinksOnly is set,
+// passing them to ErrorSink, if set.
+func (w *workers[R]) publishErrors() {
+	defer close(w.publisherDone)
+
+	if w.config.ErrorCoalesceWindow > 0 {
+		w.coalesceErrors()
+		return
+	}
+
+	for e := range w.errorsIn {
+		w.publish(e)
+	}
+}
+
+// coalesceErrors is publishErrors holding each error for ErrorCoalesceWindow, during which identical errors
+// are counted instead of being published. Errors held on closing are published without waiting.
+func (w *workers[R]) coalesceErrors() {
+	var (
+		pending []*coalescedError
+		byText  = make(map[string]*coalescedError)
+		window  = w.config.ErrorCoalesceWindow
+	)
+
+	publishHead := func() {
+		delete(byText, pending[0].err.Error())
+		w.publish(pending[0].coalesced())
+		pending = pending[1:]
+	}
+
+	for {
+		var (
+			timer  *time.Timer
+			expire <-chan time.Time
+		)
+		if len(pending) > 0 {
+			timer = time.NewTimer(time.Until(pending[0].deadline))
+			expire = timer.C
+		}
+
+		select {
+		case e, ok := <-w.errorsIn:
+			if !ok {
+				for len(pending) > 0 {
+					publishHead()
+				}
+				return
+			}
+
+			if c, ok := byText[e.Error()]; ok {
+				c.count++
+				break
+			}
+
+			c := &coalescedError{err: e, count: 1, deadline: time.Now().Add(window)}
+			pending = append(pending, c)
+			byText[e.Error()] = c
+
+		case <-expire:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		now := time.Now()
+		for len(pending) > 0 && !now.Before(pending[0].deadline) {
+			publishHead()
+		}
+	}
+}
+
+// publish passes the error to ErrorSink, if set, and sends it to the errors channel, unless SinksOnly is set.
+func (w *workers[R]) publish(e error) {
+	if w.config.ErrorSink != nil {
+		w.config.ErrorSink(e)
+
+		if w.config.SinksOnly {
+			return
+		}
+	}
+
+	w.errors <- e
+}
+
+// closeErrors closes the errors channel after all published errors have been forwarded.
+func (w *workers[R]) closeErrors() {
+	if w.errorsIn != w.errors {
+		close(w.errorsIn)
+		<-w.publisherDone
+	}
+
+	close(w.errors)
+}
+
+// reportError sends an error occurring outside of tasks to the errors channel as task errors are sent.
+// Once workers are closed, the error is dropped and counted instead, as the channel may be closed.
+func (w *workers[R]) reportError(err error) {
+	// Close does not close the channels while the lock is held.
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.isClosed {
+		w.droppedErrors.Add(1)
+		return
+	}
+
+	wk := w.pool.Get().(*worker[R])
+	defer w.pool.Put(wk)
+
+	_ = wk.sendError(err)
+}
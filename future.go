@@ -0,0 +1,28 @@
+package workers
+
+// Future is a value which becomes available later.
+type Future[T interface{}] struct {
+	done  chan struct{}
+	value T
+}
+
+func newFuture[T interface{}]() *Future[T] {
+	return &Future[T]{done: make(chan struct{})}
+}
+
+// resolve makes the value available. It must be called once.
+func (f *Future[T]) resolve(value T) {
+	f.value = value
+	close(f.done)
+}
+
+// Get blocks until the value is available and returns it.
+func (f *Future[T]) Get() T {
+	<-f.done
+	return f.value
+}
+
+// Done returns a channel which is closed once the value is available.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
@@ -0,0 +1,181 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Graph is a set of tasks depending on each other, executed so that each task starts
+// after the tasks it depends on have completed successfully. Graph is not safe for concurrent use.
+type Graph[R interface{}] struct {
+	nodes map[string]*graphNode[R]
+	ids   []string
+}
+
+type graphNode[R interface{}] struct {
+	t          task[R]
+	deps       []string
+	dependents []string
+}
+
+type graphCompletion[R interface{}] struct {
+	id     string
+	result R
+	err    error
+}
+
+// NewGraph creates an empty graph of tasks.
+func NewGraph[R interface{}]() *Graph[R] {
+	return &Graph[R]{nodes: make(map[string]*graphNode[R])}
+}
+
+// Add adds a task with the given id, depending on the tasks with deps ids, which may be added later.
+// It returns ErrInvalidConfig if a task with the same id has already been added.
+func (g *Graph[R]) Add(id string, deps []string, t interface{}) error {
+	if _, ok := g.nodes[id]; ok {
+		return fmt.Errorf("%w: duplicate task id %q", ErrInvalidConfig, id)
+	}
+
+	tt, err := newTask[R](t)
+	if err != nil {
+		return err
+	}
+
+	g.nodes[id] = &graphNode[R]{t: tt, deps: deps}
+	g.ids = append(g.ids, id)
+
+	return nil
+}
+
+// Run executes the graph tasks with workers created with config, independent tasks concurrently,
+// and returns the results of the tasks by their ids, along with the errors of the tasks joined.
+// Tasks depending on failed ones are not executed. Run returns ErrInvalidConfig
+// if a task depends on a missing task, or if dependencies form a cycle.
+func (g *Graph[R]) Run(ctx context.Context, config *Config) (map[string]R, error) {
+	remaining, err := g.link()
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := New[R](ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	w.Start(ctx)
+
+	drained := make(chan []error, 1)
+	go func() {
+		_, errs := drain(w)
+		drained <- errs
+	}()
+
+	completions := make(chan graphCompletion[R], len(g.nodes))
+	adder := w.(interface {
+		add(context.Context, task[R]) error
+	})
+	running := 0
+	add := func(id string) {
+		t := &taskNotify[R]{task: g.nodes[id].t, notify: func(result R, err error) {
+			completions <- graphCompletion[R]{id: id, result: result, err: err}
+		}}
+
+		if adder.add(context.Background(), t) == nil {
+			running++
+		}
+	}
+
+	for _, id := range g.ids {
+		if remaining[id] == 0 {
+			add(id)
+		}
+	}
+
+	results := make(map[string]R, len(g.nodes))
+	complete := func(c graphCompletion[R]) {
+		running--
+		if c.err != nil {
+			return
+		}
+
+		results[c.id] = c.result
+		for _, dependent := range g.nodes[c.id].dependents {
+			if remaining[dependent]--; remaining[dependent] == 0 {
+				add(dependent)
+			}
+		}
+	}
+
+	// Tasks remaining in the queue are never executed after workers stop.
+	stopped := w.(interface{ stopped() <-chan struct{} }).stopped()
+
+loop:
+	for running > 0 {
+		select {
+		case c := <-completions:
+			complete(c)
+
+		case <-stopped:
+			break loop
+		}
+	}
+
+	w.Close()
+
+	for len(completions) > 0 {
+		if c := <-completions; c.err == nil {
+			results[c.id] = c.result
+		}
+	}
+
+	return results, errors.Join(<-drained...)
+}
+
+// link sets the dependents of the graph nodes and returns the numbers of their dependencies.
+func (g *Graph[R]) link() (map[string]int, error) {
+	remaining := make(map[string]int, len(g.nodes))
+	for _, id := range g.ids {
+		g.nodes[id].dependents = nil
+	}
+
+	for _, id := range g.ids {
+		for _, dep := range g.nodes[id].deps {
+			node, ok := g.nodes[dep]
+			if !ok {
+				return nil, fmt.Errorf("%w: task %q depends on missing task %q", ErrInvalidConfig, id, dep)
+			}
+
+			node.dependents = append(node.dependents, id)
+			remaining[id]++
+		}
+	}
+
+	// Kahn's algorithm visits all the nodes unless there is a cycle.
+	counts := make(map[string]int, len(remaining))
+	var ready []string
+	for _, id := range g.ids {
+		counts[id] = remaining[id]
+		if counts[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+
+	visited := 0
+	for len(ready) > 0 {
+		id := ready[0]
+		ready = ready[1:]
+		visited++
+
+		for _, dependent := range g.nodes[id].dependents {
+			if counts[dependent]--; counts[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if visited < len(g.nodes) {
+		return nil, fmt.Errorf("%w: task dependencies form a cycle", ErrInvalidConfig)
+	}
+
+	return remaining, nil
+}
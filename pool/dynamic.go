@@ -1,7 +1,35 @@
 package pool
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type dynamic struct {
+	pool  sync.Pool
+	inUse atomic.Int64
+}
 
 func NewDynamic(newFn func() interface{}) Pool {
-	return &sync.Pool{New: newFn}
+	return &dynamic{pool: sync.Pool{New: newFn}}
+}
+
+func (p *dynamic) Get() interface{} {
+	el := p.pool.Get()
+	p.inUse.Add(1)
+
+	return el
+}
+
+func (p *dynamic) Put(el interface{}) {
+	p.inUse.Add(-1)
+	p.pool.Put(el)
+}
+
+func (p *dynamic) Len() int {
+	return int(p.inUse.Load())
+}
+
+func (p *dynamic) Cap() int {
+	return 0
 }
@@ -0,0 +1,48 @@
+package pool
+
+import "sync/atomic"
+
+// exclusive is a bounded pool which hands out each element to a single user at a time.
+type exclusive struct {
+	// slots holds the elements not in use, and nil for the elements not created yet.
+	slots chan interface{}
+	newFn func() interface{}
+	inUse atomic.Int64
+}
+
+// NewExclusive returns a pool of at most capacity elements, created on demand,
+// whose Get blocks while all elements are in use.
+func NewExclusive(capacity uint, newFn func() interface{}) Pool {
+	p := &exclusive{
+		slots: make(chan interface{}, capacity),
+		newFn: newFn,
+	}
+	for range capacity {
+		p.slots <- nil
+	}
+
+	return p
+}
+
+func (p *exclusive) Get() interface{} {
+	el := <-p.slots
+	if el == nil {
+		el = p.newFn()
+	}
+	p.inUse.Add(1)
+
+	return el
+}
+
+func (p *exclusive) Put(el interface{}) {
+	p.inUse.Add(-1)
+	p.slots <- el
+}
+
+func (p *exclusive) Len() int {
+	return int(p.inUse.Load())
+}
+
+func (p *exclusive) Cap() int {
+	return cap(p.slots)
+}
@@ -1,10 +1,13 @@
 package pool
 
+import "sync/atomic"
+
 type fixed struct {
 	available chan interface{}
 	all       chan interface{}
 	buf       chan interface{}
 	newFn     func() interface{}
+	inUse     atomic.Int64
 }
 
 func NewFixed(capacity uint, newFn func() interface{}) Pool {
@@ -17,6 +20,15 @@ func NewFixed(capacity uint, newFn func() interface{}) Pool {
 }
 
 func (p *fixed) Get() interface{} {
+	el := p.get()
+	p.inUse.Add(1)
+
+	return el
+}
+
+// get takes an available element, or creates one while there are fewer than capacity.
+// It never blocks: once all elements are taken, it returns one of them which may still be in use.
+func (p *fixed) get() interface{} {
 	select {
 	case el := <-p.available:
 		return el
@@ -43,6 +55,8 @@ func (p *fixed) Get() interface{} {
 }
 
 func (p *fixed) Put(el interface{}) {
+	p.inUse.Add(-1)
+
 	select {
 	case p.available <- el:
 	case p.all <- el:
@@ -50,3 +64,23 @@ func (p *fixed) Put(el interface{}) {
 	default:
 	}
 }
+
+func (p *fixed) Len() int {
+	return int(p.inUse.Load())
+}
+
+func (p *fixed) Cap() int {
+	return cap(p.all)
+}
+
+func (p *fixed) prewarm(n uint) {
+	for range n {
+		if len(p.all) == cap(p.all) {
+			return
+		}
+
+		el := p.newFn()
+		p.all <- el
+		p.available <- el
+	}
+}
@@ -3,4 +3,28 @@ package pool
 type Pool interface {
 	Get() interface{}
 	Put(interface{})
+
+	// Len returns the number of elements got and not put back.
+	Len() int
+
+	// Cap returns the maximum number of elements, or zero if the pool is unbounded.
+	Cap() int
+}
+
+// Prewarm creates n elements in the pool so that the following Get calls reuse them
+// instead of creating new ones.
+func Prewarm(p Pool, n uint) {
+	if f, ok := p.(*fixed); ok {
+		f.prewarm(n)
+		return
+	}
+
+	els := make([]interface{}, n)
+	for i := range els {
+		els[i] = p.Get()
+	}
+
+	for _, el := range els {
+		p.Put(el)
+	}
 }
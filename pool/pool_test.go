@@ -0,0 +1,107 @@
+package pool
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrewarm_Fixed(t *testing.T) {
+	var created atomic.Int32
+	p := NewFixed(4, func() interface{} {
+		created.Add(1)
+		return new(int)
+	})
+
+	Prewarm(p, 4)
+	require.Equal(t, int32(4), created.Load())
+
+	for range 4 {
+		p.Get()
+	}
+	require.Equal(t, int32(4), created.Load())
+}
+
+func TestPrewarm_Dynamic(t *testing.T) {
+	var created atomic.Int32
+	p := NewDynamic(func() interface{} {
+		created.Add(1)
+		return new(int)
+	})
+
+	Prewarm(p, 4)
+	require.Equal(t, int32(4), created.Load())
+}
+
+func TestLen(t *testing.T) {
+	newFn := func() interface{} { return new(int) }
+
+	for name, p := range map[string]Pool{
+		"fixed":     NewFixed(4, newFn),
+		"exclusive": NewExclusive(4, newFn),
+		"dynamic":   NewDynamic(newFn),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			for range 100 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					el := p.Get()
+					if p.Len() <= 0 {
+						t.Errorf("Len is %d while an element is got", p.Len())
+					}
+					p.Put(el)
+				}()
+			}
+			wg.Wait()
+
+			require.Zero(t, p.Len())
+
+			el := p.Get()
+			require.Equal(t, 1, p.Len())
+			p.Put(el)
+			require.Zero(t, p.Len())
+		})
+	}
+}
+
+func TestCap(t *testing.T) {
+	newFn := func() interface{} { return new(int) }
+
+	require.Equal(t, 4, NewFixed(4, newFn).Cap())
+	require.Equal(t, 4, NewExclusive(4, newFn).Cap())
+	require.Zero(t, NewDynamic(newFn).Cap())
+}
+
+func TestExclusive(t *testing.T) {
+	var created atomic.Int32
+	p := NewExclusive(2, func() interface{} {
+		created.Add(1)
+		return new(atomic.Int32)
+	})
+
+	var wg sync.WaitGroup
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			el := p.Get().(*atomic.Int32)
+			if users := el.Add(1); users != 1 {
+				t.Errorf("element has %d users", users)
+			}
+			el.Add(-1)
+			p.Put(el)
+		}()
+	}
+	wg.Wait()
+
+	require.LessOrEqual(t, created.Load(), int32(2))
+
+	Prewarm(p, 2)
+	require.Equal(t, int32(2), created.Load())
+}
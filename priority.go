@@ -0,0 +1,101 @@
+package workers
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+type priorityItem[R interface{}] struct {
+	t        task[R]
+	priority int
+	seq      uint64
+}
+
+// priorityItems is a heap of tasks ordered by priority and then by the order of adding.
+type priorityItems[R interface{}] []priorityItem[R]
+
+func (h priorityItems[R]) Len() int {
+	return len(h)
+}
+
+func (h priorityItems[R]) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority < h[j].priority
+	}
+
+	return h[i].seq < h[j].seq
+}
+
+func (h priorityItems[R]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *priorityItems[R]) Push(x interface{}) {
+	*h = append(*h, x.(priorityItem[R]))
+}
+
+func (h *priorityItems[R]) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// priorityQueue is a Queue dispatching tasks with lower priority values first.
+type priorityQueue[R interface{}] struct {
+	mu     sync.Mutex
+	items  priorityItems[R]
+	seq    uint64
+	notify chan struct{}
+}
+
+func newPriorityQueue[R interface{}]() *priorityQueue[R] {
+	return &priorityQueue[R]{notify: make(chan struct{}, 1)}
+}
+
+func (q *priorityQueue[R]) Enqueue(_ context.Context, t interface{}) error {
+	tt := t.(task[R])
+
+	var priority int
+	if p, ok := unwrapTo[*taskPriority[R]](tt); ok {
+		priority = p.priority
+	}
+
+	q.mu.Lock()
+	heap.Push(&q.items, priorityItem[R]{t: tt, priority: priority, seq: q.seq})
+	q.seq++
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (q *priorityQueue[R]) Dequeue(ctx context.Context) (interface{}, error) {
+	for {
+		q.mu.Lock()
+		if len(q.items) > 0 {
+			item := heap.Pop(&q.items).(priorityItem[R])
+			q.mu.Unlock()
+			return item.t, nil
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.notify:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (q *priorityQueue[R]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.items)
+}
@@ -0,0 +1,43 @@
+package workers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriorityQueue(t *testing.T) {
+	q := newPriorityQueue[int]()
+
+	add := func(value, priority int) {
+		var tt task[int] = &taskResult[int]{fn: func(context.Context) int { return value }}
+		if priority != 0 {
+			tt = &taskPriority[int]{task: tt, priority: priority}
+		}
+		require.NoError(t, q.Enqueue(context.Background(), tt))
+	}
+
+	add(1, 2)
+	add(2, 0)
+	add(3, 1)
+	add(4, 2)
+	add(5, -1)
+	require.Equal(t, 5, q.Len())
+
+	actual := make([]int, 0, 5)
+	for q.Len() > 0 {
+		tt, err := q.Dequeue(context.Background())
+		require.NoError(t, err)
+
+		value, _ := tt.(task[int]).execute(context.Background())
+		actual = append(actual, value)
+	}
+	require.Equal(t, []int{5, 2, 3, 1, 4}, actual)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := q.Dequeue(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,60 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+)
+
+// Queue is a tasks queue which can replace the internal tasks channel, e.g. to keep tasks
+// in an external storage. Tasks are opaque values which must be dequeued as they were enqueued.
+// Both Enqueue and Dequeue are expected to return an error once ctx is cancelled.
+// A dequeued value which is not an enqueued task is reported as a task error wrapping ErrInvalidConfig.
+type Queue interface {
+	Enqueue(ctx context.Context, t interface{}) error
+	Dequeue(ctx context.Context) (interface{}, error)
+	Len() int
+}
+
+// pump moves tasks from the configured queue to the dispatcher until the context is cancelled
+// or workers are closed. A task is dequeued only once the dispatcher requests it, so that tasks
+// added in the meantime, e.g. with a higher priority, are not overtaken by one held by pump.
+func (w *workers[R]) pump(ctx context.Context) {
+	for {
+		select {
+		case <-w.pull:
+		case <-w.closing:
+			return
+		case <-w.done:
+			return
+		}
+
+		t, err := w.queue.Dequeue(ctx)
+		if err != nil {
+			return
+		}
+
+		tt, ok := t.(task[R])
+		if !ok {
+			// The value is reported by a task failing with an error.
+			invalid := fmt.Errorf("%w: Queue returned %T instead of a task", ErrInvalidConfig, t)
+			tt = &taskError[R]{fn: func(context.Context) error { return invalid }}
+		}
+
+		select {
+		case w.tasks <- tt:
+		case <-w.closing:
+			return
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// queueLen returns the number of tasks waiting to be dispatched.
+func (w *workers[R]) queueLen() int {
+	if w.queue != nil {
+		return w.queue.Len()
+	}
+
+	return len(w.tasks)
+}
@@ -0,0 +1,62 @@
+package workers
+
+import (
+	"context"
+	"time"
+)
+
+// rateLimiter is a token bucket. It is not safe for concurrent use.
+type rateLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst uint) *rateLimiter {
+	if burst == 0 {
+		burst = 1
+	}
+
+	return &rateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// delay returns the time to wait for a token to become available.
+func (l *rateLimiter) delay(now time.Time) time.Duration {
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+
+	if l.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+func (l *rateLimiter) take() {
+	l.tokens--
+}
+
+// waitToken waits for the limiter to have a token available. It returns false if the context
+// is cancelled or workers are closed first.
+func (w *workers[R]) waitToken(ctx context.Context, l *rateLimiter) bool {
+	for {
+		delay := l.delay(time.Now())
+		if delay == 0 {
+			return true
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+
+		case <-w.closing:
+			timer.Stop()
+			return false
+
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package workers
+
+import (
+	"bufio"
+	"context"
+	"io"
+)
+
+// TasksFromReader reads lines from r and sends a task calling fn with each line to the returned channel,
+// to be added to workers returning errors only. If reading fails, a task returning the read error
+// is sent last. The channel is closed at the end of r, or once ctx is cancelled.
+func TasksFromReader(
+	ctx context.Context,
+	r io.Reader,
+	fn func(ctx context.Context, line string) error,
+) <-chan interface{} {
+	tasks := make(chan interface{})
+
+	go func() {
+		defer close(tasks)
+
+		send := func(t func(context.Context) error) bool {
+			select {
+			case tasks <- t:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !send(func(ctx context.Context) error { return fn(ctx, line) }) {
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			send(func(context.Context) error { return err })
+		}
+	}()
+
+	return tasks
+}
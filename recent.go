@@ -0,0 +1,48 @@
+package workers
+
+import "sync"
+
+// errorRing keeps the most recent errors.
+type errorRing struct {
+	mu   sync.Mutex
+	errs []error
+	next int
+	full bool
+}
+
+func newErrorRing(n uint) *errorRing {
+	return &errorRing{errs: make([]error, n)}
+}
+
+func (r *errorRing) add(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.errs[r.next] = err
+	r.next = (r.next + 1) % len(r.errs)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// snapshot returns the kept errors, from the oldest to the most recent one.
+func (r *errorRing) snapshot() []error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]error(nil), r.errs[:r.next]...)
+	}
+
+	return append(append([]error(nil), r.errs[r.next:]...), r.errs[:r.next]...)
+}
+
+// RecentErrors returns up to RecentErrors most recent task errors, from the oldest one.
+// The errors are kept independently of the errors channel, which is read as usual.
+func (w *workers[R]) RecentErrors() []error {
+	if w.recentErrors == nil {
+		return nil
+	}
+
+	return w.recentErrors.snapshot()
+}
@@ -0,0 +1,213 @@
+package workers
+
+import (
+	"reflect"
+	"time"
+)
+
+const resultsBufferSize = 1024
+
+type stampedResult[R interface{}] struct {
+	value R
+	at    time.Time
+}
+
+// collect forwards results received from workers to the results channel.
+func (w *workers[R]) collect() {
+	defer close(w.collectorDone)
+
+	if w.config.ResultTTL > 0 {
+		w.collectWithTTL()
+		return
+	}
+
+	isDuplicate := w.duplicates()
+	for r := range w.resultsIn {
+		if isDuplicate(r) {
+			w.droppedResults.Add(1)
+			continue
+		}
+
+		if !w.sinkResult(r) {
+			continue
+		}
+
+		select {
+		case w.results <- r:
+			w.sentResults.Add(1)
+		case <-w.dropResults:
+			w.droppedResults.Add(1)
+		case <-w.abandoned:
+			w.droppedResults.Add(1)
+		}
+	}
+}
+
+// collectWithTTL forwards results received from workers to the results channel,
+// dropping the ones which have not been received within ResultTTL.
+func (w *workers[R]) collectWithTTL() {
+	var (
+		queue       []stampedResult[R]
+		in          = w.resultsIn
+		ttl         = w.config.ResultTTL
+		isDuplicate = w.duplicates()
+	)
+
+	for in != nil || len(queue) > 0 {
+		now := time.Now()
+		for len(queue) > 0 && now.Sub(queue[0].at) >= ttl {
+			queue = queue[1:]
+			w.droppedResults.Add(1)
+		}
+
+		var (
+			out    chan R
+			head   R
+			drop   chan struct{}
+			timer  *time.Timer
+			expire <-chan time.Time
+		)
+		if len(queue) > 0 {
+			out, head, drop = w.results, queue[0].value, w.dropResults
+			timer = time.NewTimer(queue[0].at.Add(ttl).Sub(now))
+			expire = timer.C
+		}
+
+		// Stop receiving results if the buffer is full, so that workers block as they do
+		// on a full results channel.
+		recv := in
+		if len(queue) >= resultsBufferSize {
+			recv = nil
+		}
+
+		select {
+		case r, ok := <-recv:
+			if !ok {
+				in = nil
+				break
+			}
+
+			if isDuplicate(r) {
+				w.droppedResults.Add(1)
+				break
+			}
+
+			if !w.sinkResult(r) {
+				break
+			}
+
+			queue = append(queue, stampedResult[R]{value: r, at: time.Now()})
+
+		case out <- head:
+			queue = queue[1:]
+			w.sentResults.Add(1)
+
+		case <-drop:
+			queue = queue[1:]
+			w.droppedResults.Add(1)
+
+		case <-w.abandoned:
+			queue = queue[1:]
+			w.droppedResults.Add(1)
+
+		case <-expire:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// sinkResult passes the result to ResultSink, if set, and reports whether the result
+// must also be sent to the results channel.
+func (w *workers[R]) sinkResult(r R) bool {
+	if w.resultSink == nil {
+		return true
+	}
+
+	w.resultSink(r)
+	return !w.config.SinksOnly
+}
+
+// duplicates returns a function reporting whether a result has the same key as one of the previous results.
+// The function is not safe for concurrent use.
+func (w *workers[R]) duplicates() func(R) bool {
+	if w.resultKey == nil {
+		return func(R) bool { return false }
+	}
+
+	seen := make(map[interface{}]struct{})
+
+	return func(r R) bool {
+		key := w.resultKey(r)
+		if key != nil && !reflect.ValueOf(key).Comparable() {
+			w.invalidResultKeys.Add(1)
+			return false
+		}
+
+		if _, ok := seen[key]; ok {
+			return true
+		}
+
+		seen[key] = struct{}{}
+		return false
+	}
+}
+
+// closeResults closes the results channel after all collected results have been forwarded or dropped,
+// and the end marker, if any, has been sent, along with the named outputs.
+func (w *workers[R]) closeResults() {
+	if w.resultsIn != nil {
+		close(w.resultsIn)
+		<-w.collectorDone
+	}
+
+	if w.endMarker != nil {
+		w.results <- w.endMarker()
+	}
+
+	close(w.results)
+
+	for _, output := range w.outputs {
+		close(output)
+	}
+}
+
+// DroppedResults returns the number of results which have been dropped instead of being sent to the results channel.
+func (w *workers[R]) DroppedResults() uint64 {
+	return w.droppedResults.Load()
+}
+
+// Results returns an iterator yielding results with nil errors and errors with zero results,
+// in the order they are received, until both the results and errors channels are closed.
+// With Go 1.23 or later, it can be ranged over: for r, err := range w.Results().
+// The channels must not be read elsewhere.
+func (w *workers[R]) Results() func(yield func(R, error) bool) {
+	return func(yield func(R, error) bool) {
+		rc, ec := w.results, w.errors
+		for rc != nil || ec != nil {
+			select {
+			case r, ok := <-rc:
+				if !ok {
+					rc = nil
+					continue
+				}
+
+				if !yield(r, nil) {
+					return
+				}
+
+			case e, ok := <-ec:
+				if !ok {
+					ec = nil
+					continue
+				}
+
+				if !yield(*new(R), e) {
+					return
+				}
+			}
+		}
+	}
+}
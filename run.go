@@ -0,0 +1,220 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// RunAllSorted executes tasks and returns their results sorted by less,
+// along with the errors of the tasks joined. Adding tasks stops at the first one which cannot be added,
+// e.g. once workers are stopped by StopOnError. Without tasks, no workers are created,
+// and only the config is validated.
+func RunAllSorted[R interface{}](
+	ctx context.Context,
+	tasks []interface{},
+	less func(a, b R) bool,
+	config *Config,
+) ([]R, error) {
+	if len(tasks) == 0 {
+		return nil, validate(config)
+	}
+
+	w, err := New[R](ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	w.Start(ctx)
+
+	results, err := runAll(ctx, w, tasks)
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return less(results[i], results[j])
+	})
+
+	return results, err
+}
+
+// BatchHandle is a batch of tasks executed by RunAllHandle.
+type BatchHandle[R interface{}] struct {
+	cancel  context.CancelFunc
+	done    chan struct{}
+	results []R
+	err     error
+}
+
+// RunAllHandle starts executing tasks and returns a handle to cancel them and to wait for their results.
+// Without tasks, the returned handle is already completed.
+func RunAllHandle[R interface{}](ctx context.Context, tasks []interface{}, config *Config) (*BatchHandle[R], error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	if len(tasks) == 0 {
+		if err := validate(config); err != nil {
+			cancel()
+			return nil, err
+		}
+
+		h := &BatchHandle[R]{cancel: cancel, done: make(chan struct{})}
+		close(h.done)
+		return h, nil
+	}
+
+	w, err := New[R](ctx, config)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	w.Start(ctx)
+
+	h := &BatchHandle[R]{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(h.done)
+		defer cancel()
+
+		h.results, h.err = runAll(ctx, w, tasks)
+	}()
+
+	return h, nil
+}
+
+// Cancel cancels the context of the executed tasks. Tasks which have not been executed yet are not executed.
+func (h *BatchHandle[R]) Cancel() {
+	h.cancel()
+}
+
+// Results waits for the batch to complete and returns the results of the tasks in the order of completion,
+// along with the errors of the tasks joined, including context.Canceled if the batch has been cancelled.
+func (h *BatchHandle[R]) Results() ([]R, error) {
+	<-h.done
+	return h.results, h.err
+}
+
+// validate validates the config of an empty batch of tasks, which is run without creating workers.
+func validate(config *Config) error {
+	if config == nil {
+		return nil
+	}
+
+	return config.Validate()
+}
+
+// runAll adds tasks to started workers until one cannot be added, closes workers and returns the results
+// along with the errors joined. Adding errors caused by stopped workers are left out, as the errors
+// which have stopped them are returned instead, along with ctx.Err() if ctx is done.
+func runAll[R interface{}](ctx context.Context, w Workers[R], tasks []interface{}) ([]R, error) {
+	addErr := make(chan error, 1)
+	go func() {
+		defer w.Close()
+
+		for _, t := range tasks {
+			if err := w.AddTask(t); err != nil {
+				if !errors.Is(err, ErrInvalidState) {
+					addErr <- err
+				}
+				return
+			}
+		}
+	}()
+
+	results, errs := drain(w)
+
+	select {
+	case err := <-addErr:
+		errs = append(errs, err)
+	default:
+	}
+
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// Outcome is the outcome of a task executed by RunAllIndexed.
+type Outcome[R interface{}] struct {
+	// Index is the position of the task in the executed tasks.
+	Index int
+
+	// Value is the task result.
+	Value R
+
+	// Err is the task error, as sent to the errors channel, or ErrNotStarted if the task
+	// has not been executed. It is nil if the error has been dropped by ErrorFilter.
+	Err error
+}
+
+// RunAllIndexed executes tasks and returns their outcomes in the order of tasks,
+// along with the errors of the tasks joined. The outcomes of the tasks not executed,
+// e.g. because of StopOnError, have ErrNotStarted set. Without tasks, it returns no outcomes.
+func RunAllIndexed[R interface{}](ctx context.Context, tasks []interface{}, config *Config) ([]Outcome[R], error) {
+	if len(tasks) == 0 {
+		return nil, validate(config)
+	}
+
+	var mu sync.Mutex
+	outcomes := make([]Outcome[R], len(tasks))
+	indexed := make([]task[R], len(tasks))
+	for i, t := range tasks {
+		tt, err := newTask[R](t)
+		if err != nil {
+			return nil, err
+		}
+
+		outcomes[i] = Outcome[R]{Index: i, Err: ErrNotStarted}
+		indexed[i] = &taskNotify[R]{task: tt, notify: func(result R, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			outcomes[i].Value, outcomes[i].Err = result, err
+		}}
+	}
+
+	w, err := New[R](ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	w.Start(ctx)
+
+	adder := w.(interface {
+		add(context.Context, task[R]) error
+	})
+	go func() {
+		defer w.Close()
+
+		for _, t := range indexed {
+			if adder.add(context.Background(), t) != nil {
+				return
+			}
+		}
+	}()
+
+	_, errs := drain(w)
+
+	// Tasks abandoned on closing may still complete.
+	mu.Lock()
+	defer mu.Unlock()
+
+	return append([]Outcome[R](nil), outcomes...), errors.Join(errs...)
+}
+
+// drain receives results and errors until both channels are closed.
+func drain[R interface{}](w Workers[R]) ([]R, []error) {
+	var (
+		results []R
+		errs    []error
+	)
+
+	w.Results()(func(r R, err error) bool {
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			results = append(results, r)
+		}
+
+		return true
+	})
+
+	return results, errs
+}
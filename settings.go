@@ -0,0 +1,40 @@
+package workers
+
+import "context"
+
+// Settings are the effective settings of workers, resulting from their config.
+type Settings struct {
+	// Config is a copy of the config workers have been created with.
+	Config Config
+
+	// MaxWorkers is the capacity of the workers pool, or zero if the pool is dynamic.
+	MaxWorkers int
+
+	// MaxConcurrentTasks is the number of tasks executed at once, or zero if it is not limited.
+	MaxConcurrentTasks int
+
+	// TasksBufferSize is the capacity of the tasks channel, which is zero if a queue is used.
+	TasksBufferSize int
+
+	// ResultsBufferSize and ErrorsBufferSize are the capacities of the results and errors channels.
+	ResultsBufferSize int
+	ErrorsBufferSize  int
+}
+
+// NewFromConfig is New taking the config by value, so that changing it afterwards does not affect workers.
+// Settings of the created workers report the config and the settings resulting from it.
+func NewFromConfig[R interface{}](ctx context.Context, config Config) (Workers[R], error) {
+	return New[R](ctx, &config)
+}
+
+// Settings returns the effective settings of workers.
+func (w *workers[R]) Settings() Settings {
+	return Settings{
+		Config:             *w.config,
+		MaxWorkers:         w.pool.Cap(),
+		MaxConcurrentTasks: cap(w.active),
+		TasksBufferSize:    cap(w.tasks),
+		ResultsBufferSize:  cap(w.results),
+		ErrorsBufferSize:   cap(w.errors),
+	}
+}
@@ -0,0 +1,71 @@
+package workers
+
+// Stats is a snapshot of workers counters.
+type Stats struct {
+	// Enqueued is the number of added tasks.
+	Enqueued uint64
+
+	// InFlight is the number of tasks being executed.
+	InFlight int64
+
+	// QueueLen is the number of tasks waiting to be dispatched.
+	QueueLen int
+
+	// Completed is the number of executed tasks, including failed ones.
+	Completed uint64
+
+	// ActiveWorkers is the number of workers taken from the pool to execute tasks.
+	ActiveWorkers int
+
+	// Failed is the number of executed tasks which returned an error or panicked.
+	Failed uint64
+
+	// Retries is the number of task execution attempts made after failed ones.
+	Retries uint64
+
+	// InvalidResultKeys is the number of results for which ResultKey has returned a key which is not comparable.
+	// Such results are sent without being checked for duplicates.
+	InvalidResultKeys uint64
+}
+
+// Stats returns a snapshot of workers counters. The counters are not read atomically together.
+func (w *workers[R]) Stats() Stats {
+	return Stats{
+		Enqueued:      w.enqueued.Load(),
+		InFlight:      w.inFlight.Load(),
+		QueueLen:      w.queueLen(),
+		Completed:     w.completed.Load(),
+		ActiveWorkers: w.pool.Len(),
+		Failed:        w.failed.Load(),
+		Retries:       w.retries.Load(),
+
+		InvalidResultKeys: w.invalidResultKeys.Load(),
+	}
+}
+
+// QueueStats returns the current number of queued tasks along with the total numbers
+// of enqueued and dequeued ones. Sampled over time, they give the queue growth rate.
+func (w *workers[R]) QueueStats() (depth int, enqueued uint64, dequeued uint64) {
+	return w.queueLen(), w.enqueued.Load(), w.dequeued.Load()
+}
+
+// BusyWorkers returns the number of workers executing tasks.
+// For a fixed pool, the value is limited by MaxWorkers.
+func (w *workers[R]) BusyWorkers() int {
+	busy := int(w.inFlight.Load())
+	if w.config.MaxWorkers > 0 && busy > int(w.config.MaxWorkers) {
+		return int(w.config.MaxWorkers)
+	}
+
+	return busy
+}
+
+// IdleWorkers returns the number of fixed pool workers not executing tasks.
+// For a dynamic pool, the number of idle workers is unknown and zero is returned.
+func (w *workers[R]) IdleWorkers() int {
+	if w.config.MaxWorkers == 0 {
+		return 0
+	}
+
+	return int(w.config.MaxWorkers) - w.BusyWorkers()
+}
@@ -3,6 +3,8 @@ package workers
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 )
 
 type task[R interface{}] interface {
@@ -20,6 +22,34 @@ func newTask[R interface{}](fn interface{}) (task[R], error) {
 	case func(context.Context) error:
 		return &taskError[R]{fn: typed}, nil
 
+	case *panicDefault[R]:
+		t, err := newTask[R](typed.fn)
+		if err != nil {
+			return nil, err
+		}
+		return &taskPanicDefault[R]{task: t, def: typed.def}, nil
+
+	case *relativeDeadline[R]:
+		t, err := newTask[R](typed.fn)
+		if err != nil {
+			return nil, err
+		}
+		return &taskDeadline[R]{task: t, deadline: time.Now().Add(typed.timeout)}, nil
+
+	case *output[R]:
+		t, err := newTask[R](typed.fn)
+		if err != nil {
+			return nil, err
+		}
+		return &taskOutput[R]{task: t, name: typed.name}, nil
+
+	case *delayed[R]:
+		t, err := newTask[R](typed.fn)
+		if err != nil {
+			return nil, err
+		}
+		return &taskDelayed[R]{task: t, startAt: time.Now().Add(typed.delay)}, nil
+
 	default:
 		return nil, errors.New("invalid task type")
 	}
@@ -48,3 +78,229 @@ type taskError[R interface{}] struct {
 func (t *taskError[R]) execute(ctx context.Context) (R, error) {
 	return *(new(R)), t.fn(ctx)
 }
+
+// wrapper is implemented by tasks wrapping other tasks.
+type wrapper[R interface{}] interface {
+	unwrap() task[R]
+}
+
+// baseTask returns the innermost task wrapped by t.
+func baseTask[R interface{}](t task[R]) task[R] {
+	for {
+		w, ok := t.(wrapper[R])
+		if !ok {
+			return t
+		}
+		t = w.unwrap()
+	}
+}
+
+// unwrapTo returns the outermost task of type T among t and the tasks wrapped by it.
+func unwrapTo[T task[R], R interface{}](t task[R]) (T, bool) {
+	for {
+		if typed, ok := t.(T); ok {
+			return typed, true
+		}
+
+		w, ok := t.(wrapper[R])
+		if !ok {
+			return *(new(T)), false
+		}
+		t = w.unwrap()
+	}
+}
+
+// emitsResult reports whether the task result is sent to the results channel.
+func emitsResult[R interface{}](t task[R]) bool {
+	_, ok := baseTask(t).(*taskError[R])
+	return !ok
+}
+
+type panicDefault[R interface{}] struct {
+	fn  interface{}
+	def R
+}
+
+// TaskWithPanicDefault wraps a task so that, if it panics, def is sent to the results channel
+// instead of an error.
+func TaskWithPanicDefault[R interface{}](def R, fn interface{}) interface{} {
+	return &panicDefault[R]{fn: fn, def: def}
+}
+
+type taskPanicDefault[R interface{}] struct {
+	task[R]
+	def R
+}
+
+func (t *taskPanicDefault[R]) unwrap() task[R] {
+	return t.task
+}
+
+type relativeDeadline[R interface{}] struct {
+	fn      interface{}
+	timeout time.Duration
+}
+
+// TaskWithRelativeDeadline wraps a task so that it must complete within timeout from being added.
+// The time spent in the queue counts, and the task is not executed if the deadline has passed.
+func TaskWithRelativeDeadline[R interface{}](timeout time.Duration, fn interface{}) interface{} {
+	return &relativeDeadline[R]{fn: fn, timeout: timeout}
+}
+
+type taskDeadline[R interface{}] struct {
+	task[R]
+	deadline time.Time
+}
+
+func (t *taskDeadline[R]) execute(ctx context.Context) (R, error) {
+	if !time.Now().Before(t.deadline) {
+		return *(new(R)), fmt.Errorf("%w: %w", ErrTaskCancelled, context.DeadlineExceeded)
+	}
+
+	ctx, cancel := context.WithDeadline(ctx, t.deadline)
+	defer cancel()
+
+	result, err := t.task.execute(ctx)
+	return result, deadlineError(ctx, err)
+}
+
+func (t *taskDeadline[R]) unwrap() task[R] {
+	return t.task
+}
+
+type delayed[R interface{}] struct {
+	fn    interface{}
+	delay time.Duration
+}
+
+// TaskAfter wraps a task so that it is not dispatched before delay from being added elapses.
+// The task does not occupy a worker while waiting, and is discarded if workers are stopped or closed first.
+func TaskAfter[R interface{}](delay time.Duration, fn interface{}) interface{} {
+	return &delayed[R]{fn: fn, delay: delay}
+}
+
+type taskDelayed[R interface{}] struct {
+	task[R]
+	startAt time.Time
+}
+
+func (t *taskDelayed[R]) unwrap() task[R] {
+	return t.task
+}
+
+// startAt returns the time before which the task must not be dispatched, or the zero time.
+func startAt[R interface{}](t task[R]) time.Time {
+	if d, ok := unwrapTo[*taskDelayed[R]](t); ok {
+		return d.startAt
+	}
+
+	return time.Time{}
+}
+
+// taskPriority is a task added with a priority.
+type taskPriority[R interface{}] struct {
+	task[R]
+	priority int
+}
+
+func (t *taskPriority[R]) unwrap() task[R] {
+	return t.task
+}
+
+type output[R interface{}] struct {
+	fn   interface{}
+	name string
+}
+
+// TaskToOutput wraps a task so that its result is sent to the named output, one of NamedOutputs,
+// instead of the results channel.
+func TaskToOutput[R interface{}](name string, fn interface{}) interface{} {
+	return &output[R]{fn: fn, name: name}
+}
+
+type taskOutput[R interface{}] struct {
+	task[R]
+	name string
+}
+
+func (t *taskOutput[R]) unwrap() task[R] {
+	return t.task
+}
+
+// taskNotify is a task whose final result and error, after retries or a panic, are passed to notify.
+// The error is the one reported to the errors channel, nil if dropped by ErrorFilter.
+type taskNotify[R interface{}] struct {
+	task[R]
+	notify func(R, error)
+}
+
+func (t *taskNotify[R]) unwrap() task[R] {
+	return t.task
+}
+
+// taskCancelable is a task whose context is also cancelled once token is cancelled.
+type taskCancelable[R interface{}] struct {
+	task[R]
+	token context.Context
+}
+
+func (t *taskCancelable[R]) execute(ctx context.Context) (R, error) {
+	if t.token.Err() != nil {
+		return *(new(R)), fmt.Errorf("%w: %w", ErrTaskCancelled, context.Canceled)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stop := context.AfterFunc(t.token, cancel)
+	defer stop()
+
+	result, err := t.task.execute(ctx)
+	if err != nil && t.token.Err() != nil && !errors.Is(err, ErrTaskCancelled) {
+		err = fmt.Errorf("%w: %w", ErrTaskCancelled, err)
+	}
+
+	return result, err
+}
+
+func (t *taskCancelable[R]) unwrap() task[R] {
+	return t.task
+}
+
+// middleware wraps task execution.
+type middleware[R interface{}] func(next func(context.Context) (R, error)) func(context.Context) (R, error)
+
+// taskMiddleware is a task executed through a chain of middleware.
+type taskMiddleware[R interface{}] struct {
+	task[R]
+	fn func(context.Context) (R, error)
+}
+
+func (t *taskMiddleware[R]) execute(ctx context.Context) (R, error) {
+	return t.fn(ctx)
+}
+
+func (t *taskMiddleware[R]) unwrap() task[R] {
+	return t.task
+}
+
+// TaskMethod binds a method expression to obj, making a task which calls the method on obj.
+func TaskMethod[T, R interface{}](obj T, m func(T, context.Context) (R, error)) func(context.Context) (R, error) {
+	return func(ctx context.Context) (R, error) {
+		return m(obj, ctx)
+	}
+}
+
+// TaskMethodResult is TaskMethod for methods returning only a result.
+func TaskMethodResult[T, R interface{}](obj T, m func(T, context.Context) R) func(context.Context) R {
+	return func(ctx context.Context) R {
+		return m(obj, ctx)
+	}
+}
+
+// TaskMethodError is TaskMethod for methods returning only an error.
+func TaskMethodError[T interface{}](obj T, m func(T, context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return m(obj, ctx)
+	}
+}
@@ -0,0 +1,53 @@
+package workers
+
+import (
+	"context"
+	"sync"
+)
+
+type taskLocalsKey struct{}
+
+// taskLocals is a storage of values scoped to a single task execution.
+type taskLocals struct {
+	mu     sync.RWMutex
+	values map[interface{}]interface{}
+}
+
+func withTaskLocals(ctx context.Context) context.Context {
+	return context.WithValue(ctx, taskLocalsKey{}, &taskLocals{})
+}
+
+// WithTaskLocal stores a value in the storage of the task executed with ctx,
+// making it available via TaskLocal for the rest of the task execution.
+// If ctx does not belong to a task, the value is stored in the returned context.
+func WithTaskLocal(ctx context.Context, key, val interface{}) context.Context {
+	l, ok := ctx.Value(taskLocalsKey{}).(*taskLocals)
+	if !ok {
+		l = &taskLocals{}
+		ctx = context.WithValue(ctx, taskLocalsKey{}, l)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.values == nil {
+		l.values = make(map[interface{}]interface{})
+	}
+	l.values[key] = val
+
+	return ctx
+}
+
+// TaskLocal returns a value stored with WithTaskLocal in the storage of the task executed with ctx.
+func TaskLocal(ctx context.Context, key interface{}) (interface{}, bool) {
+	l, ok := ctx.Value(taskLocalsKey{}).(*taskLocals)
+	if !ok {
+		return nil, false
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	val, ok := l.values[key]
+	return val, ok
+}
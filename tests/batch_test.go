@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ygrebnov/workers"
+)
+
+func batchSize(_ context.Context, batch []int) (int, error) {
+	return len(batch), nil
+}
+
+func TestBatchStream(t *testing.T) {
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := range 10 {
+			in <- i
+		}
+	}()
+
+	// Workers are started by BatchStream.
+	w, err := workers.BatchStream(context.Background(), in, 3, 0, batchSize, nil)
+	require.NoError(t, err)
+
+	sizes := make([]int, 0, 4)
+	for size := range w.GetResults() {
+		sizes = append(sizes, size)
+	}
+	require.ElementsMatch(t, []int{3, 3, 3, 1}, sizes)
+}
+
+func TestBatchStream_Interval(t *testing.T) {
+	in := make(chan int)
+	defer close(in)
+
+	w, err := workers.BatchStream(context.Background(), in, 3, 50*time.Millisecond, batchSize, nil)
+	require.NoError(t, err)
+
+	// The incomplete batch is flushed once the interval elapses.
+	in <- 1
+	in <- 2
+
+	select {
+	case size := <-w.GetResults():
+		require.Equal(t, 2, size)
+	case <-time.After(time.Second):
+		t.Fatal("the batch is not flushed")
+	}
+}
+
+func TestBatchStream_ContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	in := make(chan int)
+	w, err := workers.BatchStream(ctx, in, 3, 0, batchSize, nil)
+	require.NoError(t, err)
+
+	in <- 1
+	in <- 2
+	cancel()
+
+	// The incomplete batch is reported.
+	err = <-w.GetErrors()
+	require.ErrorIs(t, err, workers.ErrNotStarted)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestBatchStream_Closed(t *testing.T) {
+	for range 20 {
+		in := make(chan int)
+		stop := make(chan struct{})
+		go func() {
+			defer close(in)
+			for i := 0; ; i++ {
+				select {
+				case in <- i:
+				case <-stop:
+					return
+				}
+			}
+		}()
+
+		w, err := workers.BatchStream(context.Background(), in, 2, 0, batchSize, nil)
+		require.NoError(t, err)
+
+		// Batches are still received while closing, and those which cannot be added are dropped.
+		time.Sleep(time.Millisecond)
+		w.Close()
+		close(stop)
+
+		for range w.GetResults() {
+		}
+	}
+}
+
+func TestBatchStream_InvalidSize(t *testing.T) {
+	_, err := workers.BatchStream(context.Background(), make(chan int), 0, 0, batchSize, nil)
+	require.ErrorIs(t, err, workers.ErrInvalidConfig)
+}
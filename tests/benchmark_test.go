@@ -2,6 +2,7 @@ package tests
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/ygrebnov/workers"
@@ -24,10 +25,13 @@ func BenchmarkWorkers(b *testing.B) {
 	for _, test := range tests {
 		b.Run(test.name, func(b *testing.B) {
 			for range b.N {
-				w := workers.New[string](
+				w, err := workers.New[string](
 					context.Background(),
 					&workers.Config{MaxWorkers: test.maxWorkers, StartImmediately: true},
 				)
+				if err != nil {
+					b.Fatal(err)
+				}
 				actual := make([]string, 0, len(test.tasks))
 				errors := make([]error, 0, len(test.tasks))
 
@@ -43,7 +47,7 @@ func BenchmarkWorkers(b *testing.B) {
 				}()
 
 				for _, task := range test.tasks {
-					err := w.AddTask(task)
+					err = w.AddTask(task)
 					if err != nil {
 						b.Fatal(err)
 					}
@@ -52,3 +56,31 @@ func BenchmarkWorkers(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkSerializedResults(b *testing.B) {
+	for _, serialized := range []bool{false, true} {
+		b.Run(fmt.Sprintf("serialized_%t", serialized), func(b *testing.B) {
+			w, err := workers.New[string](
+				context.Background(),
+				&workers.Config{StartImmediately: true, SerializedResults: serialized},
+			)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			go func() {
+				for range w.GetResults() {
+				}
+			}()
+
+			task := newTaskResult(2, 0)
+			for range b.N {
+				if err = w.AddTask(task); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			w.Close()
+		})
+	}
+}
@@ -2,7 +2,12 @@ package tests
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -154,7 +159,7 @@ func TestFunctional(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			w := workers.New[string](
+			w, err := workers.New[string](
 				context.Background(),
 				&workers.Config{
 					MaxWorkers:       test.maxWorkers,
@@ -162,6 +167,7 @@ func TestFunctional(t *testing.T) {
 					StopOnError:      test.stopOnError,
 				},
 			)
+			require.NoError(t, err)
 
 			done := make(chan struct{}, 1)
 
@@ -180,12 +186,12 @@ func TestFunctional(t *testing.T) {
 				}
 
 				require.ElementsMatch(t, actual, test.expectedResults)
-				require.ElementsMatch(t, errors, test.expectedErrors)
+				require.ElementsMatch(t, errorMessages(errors), errorMessages(test.expectedErrors))
 				done <- struct{}{}
 			}()
 
 			for _, task := range test.tasks {
-				err := w.AddTask(task)
+				err = w.AddTask(task)
 				require.NoError(t, err)
 			}
 
@@ -193,3 +199,934 @@ func TestFunctional(t *testing.T) {
 		})
 	}
 }
+
+func TestRequireCancellableContext(t *testing.T) {
+	config := &workers.Config{RequireCancellableContext: true}
+
+	w, err := workers.New[string](context.Background(), config)
+	require.ErrorIs(t, err, workers.ErrInvalidConfig)
+	require.Nil(t, w)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w, err = workers.New[string](ctx, config)
+	require.NoError(t, err)
+	require.NotNil(t, w)
+}
+
+func TestConfigValidate(t *testing.T) {
+	config := &workers.Config{
+		MaxWorkers:        4,
+		TasksBufferSize:   16,
+		StartImmediately:  true,
+		StopOnError:       true,
+		TaskTimeout:       time.Second,
+		RetryAttempts:     2,
+		ReliableResults:   true,
+		SerializedResults: true,
+		RecentErrors:      8,
+		Name:              "pool",
+	}
+	require.NoError(t, config.Validate())
+
+	w, err := workers.New[string](context.Background(), config)
+	require.NoError(t, err)
+	w.Close()
+
+	for _, config = range []*workers.Config{
+		{MaxWorkers: 4, DynamicPoolMax: 4},
+		{ReliableResults: true, ResultTTL: time.Second},
+		{HeartbeatInterval: time.Second},
+		{ResultEncoder: func(string) ([]byte, error) { return nil, nil }},
+		{Priority: true, Queue: newSliceQueue()},
+		{WorkerInit: func() (interface{}, error) { return nil, nil }},
+		{MaxQueueDepth: 2},
+	} {
+		require.ErrorIs(t, config.Validate(), workers.ErrInvalidConfig)
+
+		_, err = workers.New[string](context.Background(), config)
+		require.ErrorIs(t, err, workers.ErrInvalidConfig)
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	config := workers.Config{
+		MaxWorkers:          4,
+		TasksBufferSize:     16,
+		MaxQueueDepth:       8,
+		StopOnError:         true,
+		TaskTimeout:         time.Second,
+		RetryAttempts:       2,
+		ResultsBeforeErrors: true,
+		RecentErrors:        8,
+		Name:                "pool",
+		WorkerInit:          func() (interface{}, error) { return nil, nil },
+	}
+
+	w, err := workers.NewFromConfig[string](context.Background(), config)
+	require.NoError(t, err)
+
+	// Changing the config after creating workers does not affect them.
+	config.Name = "changed"
+
+	settings := w.Settings()
+	require.Equal(t, "pool", settings.Config.Name)
+	require.Equal(t, uint(16), settings.Config.TasksBufferSize)
+	require.Equal(t, uint(8), settings.Config.MaxQueueDepth)
+	require.True(t, settings.Config.StopOnError)
+	require.Equal(t, time.Second, settings.Config.TaskTimeout)
+	require.Equal(t, 4, settings.MaxWorkers)
+	require.Equal(t, 4, settings.MaxConcurrentTasks)
+	require.Equal(t, 16, settings.TasksBufferSize)
+	require.Zero(t, settings.ResultsBufferSize)
+	require.Equal(t, 1024, settings.ErrorsBufferSize)
+
+	w.Close()
+
+	w, err = workers.NewFromConfig[string](context.Background(), workers.Config{Priority: true, DynamicPoolMax: 2})
+	require.NoError(t, err)
+
+	settings = w.Settings()
+	require.Zero(t, settings.MaxWorkers)
+	require.Equal(t, 2, settings.MaxConcurrentTasks)
+	require.Zero(t, settings.TasksBufferSize)
+	require.Equal(t, 1024, settings.ResultsBufferSize)
+
+	w.Close()
+
+	_, err = workers.NewFromConfig[string](context.Background(), workers.Config{MaxWorkers: 4, DynamicPoolMax: 4})
+	require.ErrorIs(t, err, workers.ErrInvalidConfig)
+}
+
+func TestEmitResultOnError(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, EmitResultOnError: true},
+	)
+	require.NoError(t, err)
+
+	err = w.AddTask(func(context.Context) (string, error) {
+		return "partial", errBasic
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "partial", <-w.GetResults())
+	require.ErrorIs(t, <-w.GetErrors(), errBasic)
+}
+
+func TestTaskLocal(t *testing.T) {
+	type requestIDKey struct{}
+
+	w, err := workers.New[string](context.Background(), &workers.Config{StartImmediately: true})
+	require.NoError(t, err)
+
+	readRequestID := func(ctx context.Context) string {
+		v, ok := workers.TaskLocal(ctx, requestIDKey{})
+		if !ok {
+			return ""
+		}
+		return v.(string)
+	}
+
+	setBefore := make(chan bool, 1)
+	err = w.AddTask(func(ctx context.Context) string {
+		_, ok := workers.TaskLocal(ctx, requestIDKey{})
+		setBefore <- ok
+
+		// The returned context is not used, the value is available via the task context.
+		_ = workers.WithTaskLocal(ctx, requestIDKey{}, "request-1")
+
+		return readRequestID(ctx)
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "request-1", <-w.GetResults())
+	require.False(t, <-setBefore)
+}
+
+func TestFairAdmission(t *testing.T) {
+	// A single worker executes tasks in the order of enqueueing, which is the order of admission.
+	w, err := workers.New[int](
+		context.Background(),
+		&workers.Config{TasksBufferSize: 1, FairAdmission: true, DynamicPoolMax: 1},
+	)
+	require.NoError(t, err)
+
+	// Fill the tasks queue.
+	require.NoError(t, w.AddTask(func(context.Context) int { return -1 }))
+
+	const producers = 5
+
+	errs := make(chan error, producers)
+	for i := range producers {
+		go func() {
+			errs <- w.AddTask(func(context.Context) int { return i })
+		}()
+
+		// Let the producer block on the full queue before the next one arrives.
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	w.Start(context.Background())
+
+	for range producers {
+		require.NoError(t, <-errs)
+	}
+
+	admitted := make([]int, 0, producers+1)
+	for range producers + 1 {
+		admitted = append(admitted, <-w.GetResults())
+	}
+
+	require.Equal(t, []int{-1, 0, 1, 2, 3, 4}, admitted)
+}
+
+func TestTaskWithPanicDefault(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{StartImmediately: true})
+	require.NoError(t, err)
+
+	require.NoError(t, w.AddTask(workers.TaskWithPanicDefault("default", panicTaskResultError)))
+	require.NoError(t, w.AddTask(workers.TaskWithPanicDefault("default", basicTaskResult)))
+
+	w.Close()
+
+	actual := make([]string, 0, 2)
+	for result := range w.GetResults() {
+		actual = append(actual, result)
+	}
+	require.ElementsMatch(t, append(generateExpected(1, basicTaskResult), "default"), actual)
+	require.Empty(t, w.GetErrors())
+
+	require.Error(t, w.AddTask(workers.TaskWithPanicDefault("default", 1)))
+}
+
+func TestAddTasks(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{TasksBufferSize: 4})
+	require.NoError(t, err)
+
+	n, err := w.AddTasks([]interface{}{basicTaskResult, basicTaskResult})
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	n, err = w.AddTasks([]interface{}{basicTaskResult, 1, basicTaskResult})
+	require.Error(t, err)
+	require.Equal(t, 1, n)
+
+	w.Close()
+
+	n, err = w.AddTasks([]interface{}{basicTaskResult})
+	require.ErrorIs(t, err, workers.ErrInvalidState)
+	require.Zero(t, n)
+	require.Equal(t, 3, w.Undispatched())
+}
+
+func TestTryAddTaskContext(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{TasksBufferSize: 1})
+	require.NoError(t, err)
+
+	ok, err := w.TryAddTaskContext(context.Background(), basicTaskResult)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// The queue is full, as workers are not started.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	ok, err = w.TryAddTaskContext(ctx, basicTaskResult)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, err = w.TryAddTaskContext(context.Background(), 1)
+	require.Error(t, err)
+
+	w.Close()
+
+	ok, err = w.TryAddTaskContext(context.Background(), basicTaskResult)
+	require.ErrorIs(t, err, workers.ErrInvalidState)
+	require.False(t, ok)
+}
+
+func TestTryAddTaskContext_MaxQueueDepth(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{TasksBufferSize: 8, MaxQueueDepth: 2},
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Tasks are added while there is room in the queue, even with a done context.
+	for range 2 {
+		ok, err := w.TryAddTaskContext(ctx, basicTaskResult)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	ok, err := w.TryAddTaskContext(ctx, basicTaskResult)
+	require.ErrorIs(t, err, workers.ErrQueueFull)
+	require.False(t, ok)
+
+	ok, err = w.TryAddTaskContext(context.Background(), basicTaskResult)
+	require.ErrorIs(t, err, workers.ErrQueueFull)
+	require.False(t, ok)
+
+	w.Close()
+	require.Equal(t, 2, w.Undispatched())
+}
+
+func TestTryAddTaskContext_FairAdmission(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{FairAdmission: true})
+	require.NoError(t, err)
+
+	// The adder blocks on the unbuffered queue, holding the admission.
+	added := make(chan error, 1)
+	go func() {
+		added <- w.AddTask(basicTaskResult)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	ok, err := w.TryAddTaskContext(ctx, basicTaskResult)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Less(t, time.Since(start), time.Second)
+
+	w.Close()
+	require.ErrorIs(t, <-added, workers.ErrInvalidState)
+}
+
+func TestAddTaskCancelable(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{StartImmediately: true})
+	require.NoError(t, err)
+
+	blocking := func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+
+	cancel, err := w.AddTaskCancelable(blocking)
+	require.NoError(t, err)
+	require.NoError(t, w.AddTask(basicTaskResult))
+
+	require.Equal(t, generateExpected(1, basicTaskResult)[0], <-w.GetResults())
+
+	cancel()
+	err = <-w.GetErrors()
+	require.ErrorIs(t, err, workers.ErrTaskCancelled)
+	require.ErrorIs(t, err, context.Canceled)
+
+	w.Close()
+
+	_, err = w.AddTaskCancelable(basicTaskResult)
+	require.ErrorIs(t, err, workers.ErrInvalidState)
+}
+
+func TestPanicHandler(t *testing.T) {
+	recovered := make(chan interface{}, 2)
+	w, err := workers.New[string](context.Background(), &workers.Config{
+		StartImmediately: true,
+		PanicHandler: func(r interface{}) {
+			recovered <- r
+			panic("handler panic")
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, w.AddTask(panicTaskResultError))
+	require.Equal(t, "panic", <-recovered)
+	require.ErrorIs(t, <-w.GetErrors(), workers.ErrTaskPanicked)
+
+	w.Close()
+}
+
+func TestPanicError(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{StartImmediately: true})
+	require.NoError(t, err)
+
+	require.NoError(t, w.AddTask(panicTaskResultError))
+
+	err = <-w.GetErrors()
+	require.ErrorIs(t, err, workers.ErrTaskPanicked)
+	require.EqualError(t, err, errPanic.Error())
+
+	var panicErr *workers.PanicError
+	require.ErrorAs(t, err, &panicErr)
+	require.Equal(t, "panic", panicErr.Value())
+	require.Contains(t, string(panicErr.Stack()), "tests.newPanicTaskResultError.func1")
+
+	w.Close()
+}
+
+type greeter struct {
+	name string
+}
+
+func (g *greeter) greet(context.Context) (string, error) {
+	return "hello, " + g.name, nil
+}
+
+func (g *greeter) shout(context.Context) string {
+	return "HELLO, " + g.name
+}
+
+func (g *greeter) fail(context.Context) error {
+	return errBasic
+}
+
+func TestTaskMethod(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{StartImmediately: true})
+	require.NoError(t, err)
+
+	g := &greeter{name: "workers"}
+	require.NoError(t, w.AddTask(workers.TaskMethod(g, (*greeter).greet)))
+	require.NoError(t, w.AddTask(workers.TaskMethodResult(g, (*greeter).shout)))
+	require.NoError(t, w.AddTask(workers.TaskMethodError(g, (*greeter).fail)))
+
+	w.Close()
+
+	actual := make([]string, 0, 2)
+	for result := range w.GetResults() {
+		actual = append(actual, result)
+	}
+	require.ElementsMatch(t, []string{"hello, workers", "HELLO, workers"}, actual)
+	require.ErrorIs(t, <-w.GetErrors(), errBasic)
+}
+
+func TestTaskWithRelativeDeadline(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{TasksBufferSize: 2})
+	require.NoError(t, err)
+
+	var executed bool
+	task := func(context.Context) (string, error) {
+		executed = true
+		return "", nil
+	}
+
+	require.NoError(t, w.AddTask(workers.TaskWithRelativeDeadline[string](50*time.Millisecond, task)))
+	require.NoError(t, w.AddTask(workers.TaskWithRelativeDeadline[string](time.Second, basicTaskResult)))
+
+	// The first task waits in the queue longer than its deadline.
+	time.Sleep(100 * time.Millisecond)
+	w.Start(context.Background())
+
+	err = <-w.GetErrors()
+	require.ErrorIs(t, err, workers.ErrTaskCancelled)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, generateExpected(1, basicTaskResult)[0], <-w.GetResults())
+
+	w.Close()
+	require.False(t, executed)
+}
+
+func TestTaskAfter(t *testing.T) {
+	t.Run("delayed", func(t *testing.T) {
+		w, err := workers.New[string](context.Background(), &workers.Config{StartImmediately: true})
+		require.NoError(t, err)
+
+		started := make(chan time.Time, 1)
+		task := func(context.Context) string {
+			started <- time.Now()
+			return "delayed"
+		}
+
+		// The delay leaves a wide margin for the task added without a delay to complete first.
+		const delay = 500 * time.Millisecond
+
+		added := time.Now()
+		require.NoError(t, w.AddTask(workers.TaskAfter[string](delay, task)))
+		require.NoError(t, w.AddTask(newTaskResult(5, 0)))
+
+		// The task added without a delay is not blocked by the delayed one.
+		require.Equal(t, generateExpected(1, newTaskResult(5, 0))[0], <-w.GetResults())
+		require.Equal(t, "delayed", <-w.GetResults())
+		require.GreaterOrEqual(t, (<-started).Sub(added), delay)
+
+		w.Close()
+	})
+
+	t.Run("discarded on close", func(t *testing.T) {
+		w, err := workers.New[string](context.Background(), &workers.Config{StartImmediately: true})
+		require.NoError(t, err)
+
+		var executed atomic.Bool
+		task := func(context.Context) string {
+			executed.Store(true)
+			return ""
+		}
+
+		require.NoError(t, w.AddTask(workers.TaskAfter[string](time.Hour, task)))
+		require.Eventually(t, func() bool {
+			_, _, dequeued := w.QueueStats()
+			return dequeued == 1
+		}, 10*time.Second, time.Millisecond)
+
+		w.Close()
+		require.Empty(t, w.GetResults())
+		require.False(t, executed.Load())
+	})
+}
+
+func TestTaskTimeout(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, TaskTimeout: 100 * time.Millisecond},
+	)
+	require.NoError(t, err)
+
+	slow := func(ctx context.Context) (string, error) {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(time.Second):
+			return "slow", nil
+		}
+	}
+	fast := newTaskResultError(5, 10*time.Millisecond)
+
+	for _, task := range []interface{}{fast, slow, fast, slow} {
+		require.NoError(t, w.AddTask(task))
+	}
+
+	w.Close()
+
+	actual := make([]string, 0, 2)
+	for result := range w.GetResults() {
+		actual = append(actual, result)
+	}
+	require.ElementsMatch(t, generateExpected(2, fast), actual)
+
+	errs := make([]error, 0, 2)
+	for err = range w.GetErrors() {
+		require.ErrorIs(t, err, workers.ErrTaskCancelled)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		errs = append(errs, err)
+	}
+	require.Len(t, errs, 2)
+}
+
+// newFlakyTask returns a task failing the given number of times before succeeding.
+func newFlakyTask(failures int32) (func(context.Context) (string, error), *atomic.Int32) {
+	var calls atomic.Int32
+	return func(context.Context) (string, error) {
+		if calls.Add(1) <= failures {
+			return "", errBasic
+		}
+		return "ok", nil
+	}, &calls
+}
+
+func TestRetry(t *testing.T) {
+	var backoffs []uint
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{
+			MaxWorkers:       1,
+			StartImmediately: true,
+			StopOnError:      true,
+			RetryAttempts:    3,
+			RetryBackoff: func(attempt uint) time.Duration {
+				backoffs = append(backoffs, attempt)
+				return 10 * time.Millisecond
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	// The task eventually succeeds and does not stop execution.
+	task, calls := newFlakyTask(2)
+	require.NoError(t, w.AddTask(task))
+	require.Equal(t, "ok", <-w.GetResults())
+	require.Equal(t, int32(3), calls.Load())
+	require.Equal(t, []uint{1, 2}, backoffs)
+	require.Equal(t, uint64(2), w.Stats().Retries)
+
+	// Only the final error is forwarded.
+	task, calls = newFlakyTask(10)
+	require.NoError(t, w.AddTask(task))
+	require.ErrorIs(t, <-w.GetErrors(), errBasic)
+	require.Equal(t, int32(4), calls.Load())
+	require.Equal(t, uint64(5), w.Stats().Retries)
+
+	w.Close()
+	require.Empty(t, w.GetErrors())
+}
+
+func TestRetry_ContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w, err := workers.New[string](
+		ctx,
+		&workers.Config{
+			StartImmediately: true,
+			RetryAttempts:    3,
+			RetryBackoff:     func(uint) time.Duration { return time.Hour },
+		},
+	)
+	require.NoError(t, err)
+
+	task, calls := newFlakyTask(10)
+	require.NoError(t, w.AddTask(task))
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	require.ErrorIs(t, <-w.GetErrors(), errBasic)
+	require.Equal(t, int32(1), calls.Load())
+
+	w.Close()
+}
+
+func TestRetry_TaskCancelled(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, RetryAttempts: 3},
+	)
+	require.NoError(t, err)
+
+	var calls atomic.Int32
+	started := make(chan struct{})
+	cancel, err := w.AddTaskCancelable(func(ctx context.Context) (string, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	require.NoError(t, err)
+
+	<-started
+	cancel()
+
+	require.ErrorIs(t, <-w.GetErrors(), workers.ErrTaskCancelled)
+	require.Equal(t, int32(1), calls.Load())
+
+	w.Close()
+}
+
+func TestMiddleware(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	record := func(call string) {
+		mu.Lock()
+		calls = append(calls, call)
+		mu.Unlock()
+	}
+
+	newMiddleware := func(name string) func(func(context.Context) (string, error)) func(context.Context) (string, error) {
+		return func(next func(context.Context) (string, error)) func(context.Context) (string, error) {
+			return func(ctx context.Context) (string, error) {
+				record(name + " before")
+				result, err := next(ctx)
+				record(name + " after")
+				return name + "(" + result + ")", err
+			}
+		}
+	}
+
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{
+			StartImmediately: true,
+			Middleware:       []interface{}{newMiddleware("outer"), newMiddleware("inner")},
+		},
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, w.AddTask(func(context.Context) string {
+		record("task")
+		return "result"
+	}))
+
+	require.Equal(t, "outer(inner(result))", <-w.GetResults())
+	require.Equal(t, []string{"outer before", "inner before", "task", "inner after", "outer after"}, calls)
+}
+
+func TestMiddleware_InvalidType(t *testing.T) {
+	_, err := workers.New[string](
+		context.Background(),
+		&workers.Config{Middleware: []interface{}{func(context.Context) {}}},
+	)
+	require.ErrorIs(t, err, workers.ErrInvalidConfig)
+}
+
+func TestMaxQueueDepth(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{TasksBufferSize: 8, MaxQueueDepth: 2},
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, w.AddTask(basicTaskResult))
+	require.NoError(t, w.AddTask(basicTaskResult))
+	require.ErrorIs(t, w.AddTask(basicTaskResult), workers.ErrQueueFull)
+
+	// Dispatching tasks frees the queue.
+	w.Start(context.Background())
+	require.Eventually(t, func() bool {
+		depth, _, _ := w.QueueStats()
+		return depth == 0
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, w.AddTask(basicTaskResult))
+
+	w.Close()
+	require.Len(t, w.GetResults(), 3)
+}
+
+func TestContextValues(t *testing.T) {
+	type tenantKey struct{}
+
+	w, err := workers.New[string](context.Background(), &workers.Config{
+		StartImmediately: true,
+		ContextValues: func(ctx context.Context) context.Context {
+			return context.WithValue(ctx, tenantKey{}, "tenant")
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, w.AddTask(func(ctx context.Context) string {
+		return ctx.Value(tenantKey{}).(string)
+	}))
+	require.Equal(t, "tenant", <-w.GetResults())
+
+	w.Close()
+}
+
+func TestErrorFilter(t *testing.T) {
+	errTransformed := errors.New("transformed")
+	errOther := errors.New("other")
+	filter := func(err error) error {
+		switch {
+		case errors.Is(err, context.Canceled):
+			return nil
+		case errors.Is(err, errOther):
+			return fmt.Errorf("%w: %w", errTransformed, err)
+		default:
+			return err
+		}
+	}
+	cancelled := func(context.Context) error { return context.Canceled }
+
+	t.Run("drop and transform", func(t *testing.T) {
+		w, err := workers.New[string](
+			context.Background(),
+			&workers.Config{StartImmediately: true, ErrorFilter: filter},
+		)
+		require.NoError(t, err)
+
+		require.NoError(t, w.AddTask(cancelled))
+		require.NoError(t, w.AddTask(newErrorTaskResultError(errOther)))
+
+		err = <-w.GetErrors()
+		require.ErrorIs(t, err, errTransformed)
+		require.ErrorIs(t, err, errOther)
+
+		w.Close()
+		require.Empty(t, w.GetErrors())
+		require.Equal(t, uint64(1), w.Stats().Failed)
+	})
+
+	t.Run("dropped error does not stop", func(t *testing.T) {
+		w, err := workers.New[string](
+			context.Background(),
+			&workers.Config{StartImmediately: true, StopOnError: true, ErrorFilter: filter},
+		)
+		require.NoError(t, err)
+
+		require.NoError(t, w.AddTask(cancelled))
+		require.Eventually(t, func() bool {
+			return w.Stats().Completed == 1
+		}, time.Second, time.Millisecond)
+
+		require.NoError(t, w.AddTask(newTaskResult(1, 0)))
+		require.Equal(t, "s", <-w.GetResults())
+
+		w.Close()
+		require.Empty(t, w.GetErrors())
+	})
+}
+
+func TestName(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, Name: "pipeline-A"},
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, w.AddTask(errorTaskResultError))
+
+	err = <-w.GetErrors()
+	require.ErrorIs(t, err, errBasic)
+	require.EqualError(t, err, "[pipeline-A] error")
+
+	w.Close()
+}
+
+func TestErrorCoalesceWindow(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, ErrorCoalesceWindow: 200 * time.Millisecond},
+	)
+	require.NoError(t, err)
+
+	errOther := errors.New("other")
+	for range 10 {
+		require.NoError(t, w.AddTask(errorTaskResultError))
+	}
+	require.NoError(t, w.AddTask(newErrorTaskResultError(errOther)))
+
+	counts := make(map[string]int)
+	for range 2 {
+		err = <-w.GetErrors()
+		if errors.Is(err, errBasic) {
+			counts["basic"] = workers.ExtractRepeatCount(err)
+		} else {
+			require.Equal(t, errOther, err)
+			counts["other"] = workers.ExtractRepeatCount(err)
+		}
+	}
+	require.Equal(t, map[string]int{"basic": 10, "other": 1}, counts)
+
+	w.Close()
+	require.Empty(t, w.GetErrors())
+	require.Zero(t, workers.ExtractRepeatCount(nil))
+}
+
+func TestRecentErrors(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, MaxWorkers: 1, RecentErrors: 3},
+	)
+	require.NoError(t, err)
+
+	require.Empty(t, w.RecentErrors())
+
+	errs := make([]error, 5)
+	for i := range errs {
+		errs[i] = fmt.Errorf("error %d", i)
+		require.NoError(t, w.AddTask(newErrorTaskResultError(errs[i])))
+
+		// Errors are produced in order.
+		require.Equal(t, errs[i], <-w.GetErrors())
+	}
+
+	require.Equal(t, errs[2:], w.RecentErrors())
+
+	w.Close()
+}
+
+func TestRateLimit(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, TasksBufferSize: 10, RateLimit: 20, RateBurst: 2},
+	)
+	require.NoError(t, err)
+
+	const n = 10
+	start := time.Now()
+	for range n {
+		require.NoError(t, w.AddTask(newTaskResult(1, 0)))
+	}
+
+	for range n {
+		<-w.GetResults()
+	}
+
+	// The first tasks are dispatched at once, the following ones at the limited rate.
+	require.GreaterOrEqual(t, time.Since(start), (n-2)*50*time.Millisecond)
+
+	w.Close()
+}
+
+func TestWorkerInit(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		created  int
+		shutdown []interface{}
+	)
+
+	w, err := workers.New[int](context.Background(), &workers.Config{
+		StartImmediately: true,
+		MaxWorkers:       2,
+		WorkerInit: func() (interface{}, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			created++
+			if created == 1 {
+				return nil, errBasic
+			}
+			return created, nil
+		},
+		WorkerShutdown: func(state interface{}) {
+			shutdown = append(shutdown, state)
+		},
+	})
+	require.NoError(t, err)
+
+	task := func(ctx context.Context) int {
+		return workers.WorkerState(ctx).(int)
+	}
+
+	// The first initialization fails, and is retried for the next task.
+	require.NoError(t, w.AddTask(task))
+	require.ErrorIs(t, <-w.GetErrors(), errBasic)
+
+	require.NoError(t, w.AddTask(task))
+	select {
+	case r := <-w.GetResults():
+		require.Equal(t, 2, r)
+	case e := <-w.GetErrors():
+		t.Fatal(e)
+	}
+
+	w.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, shutdown, created-1)
+	require.Contains(t, shutdown, 2)
+}
+
+func TestWorkerInit_Exclusive(t *testing.T) {
+	const maxWorkers = 3
+
+	w, err := workers.New[int](context.Background(), &workers.Config{
+		StartImmediately: true,
+		MaxWorkers:       maxWorkers,
+		TasksBufferSize:  100,
+		WorkerInit: func() (interface{}, error) {
+			return new(atomic.Int32), nil
+		},
+	})
+	require.NoError(t, err)
+
+	for range 100 {
+		require.NoError(t, w.AddTask(func(ctx context.Context) int {
+			users := workers.WorkerState(ctx).(*atomic.Int32)
+			n := users.Add(1)
+			time.Sleep(time.Millisecond)
+			users.Add(-1)
+			return int(n)
+		}))
+	}
+
+	for range 100 {
+		select {
+		case r := <-w.GetResults():
+			require.Equal(t, 1, r, "worker state used by several tasks at once")
+		case e := <-w.GetErrors():
+			t.Fatal(e)
+		}
+		require.LessOrEqual(t, w.Stats().ActiveWorkers, maxWorkers)
+	}
+
+	w.Close()
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ygrebnov/workers"
+)
+
+func TestGraph(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	record := func(event string) {
+		mu.Lock()
+		order = append(order, event)
+		mu.Unlock()
+	}
+
+	// b and c wait for each other, so they must run concurrently.
+	bStarted, cStarted := make(chan struct{}), make(chan struct{})
+	node := func(id string, started, other chan struct{}) func(context.Context) (string, error) {
+		return func(context.Context) (string, error) {
+			record(id)
+			if started != nil {
+				close(started)
+				select {
+				case <-other:
+				case <-time.After(time.Second):
+					return "", errBasic
+				}
+			}
+			return id, nil
+		}
+	}
+
+	g := workers.NewGraph[string]()
+	require.NoError(t, g.Add("d", []string{"b", "c"}, node("d", nil, nil)))
+	require.NoError(t, g.Add("b", []string{"a"}, node("b", bStarted, cStarted)))
+	require.NoError(t, g.Add("c", []string{"a"}, node("c", cStarted, bStarted)))
+	require.NoError(t, g.Add("a", nil, node("a", nil, nil)))
+	require.ErrorIs(t, g.Add("a", nil, node("a", nil, nil)), workers.ErrInvalidConfig)
+
+	results, err := g.Run(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"a": "a", "b": "b", "c": "c", "d": "d"}, results)
+
+	require.Len(t, order, 4)
+	require.Equal(t, "a", order[0])
+	require.ElementsMatch(t, []string{"b", "c"}, order[1:3])
+	require.Equal(t, "d", order[3])
+}
+
+func TestGraph_Errors(t *testing.T) {
+	g := workers.NewGraph[string]()
+	require.NoError(t, g.Add("a", nil, errorTaskResultError))
+	require.NoError(t, g.Add("b", []string{"a"}, newTaskResultError(1, 0)))
+	require.NoError(t, g.Add("c", nil, newTaskResultError(1, 0)))
+
+	// Tasks depending on failed ones are not executed.
+	results, err := g.Run(context.Background(), nil)
+	require.ErrorIs(t, err, errBasic)
+	require.Equal(t, map[string]string{"c": "s"}, results)
+
+	g = workers.NewGraph[string]()
+	require.NoError(t, g.Add("a", []string{"b"}, basicTaskResult))
+	require.NoError(t, g.Add("b", []string{"a"}, basicTaskResult))
+	_, err = g.Run(context.Background(), nil)
+	require.ErrorIs(t, err, workers.ErrInvalidConfig)
+
+	g = workers.NewGraph[string]()
+	require.NoError(t, g.Add("a", []string{"missing"}, basicTaskResult))
+	_, err = g.Run(context.Background(), nil)
+	require.ErrorIs(t, err, workers.ErrInvalidConfig)
+}
+
+func TestGraph_StopOnError(t *testing.T) {
+	g := workers.NewGraph[string]()
+	require.NoError(t, g.Add("a", nil, errorTaskResultError))
+	require.NoError(t, g.Add("b", nil, newTaskResultError(1, 300*time.Millisecond)))
+	require.NoError(t, g.Add("c", []string{"b"}, newTaskResultError(1, 0)))
+
+	// Run returns once workers stop, without waiting for c.
+	results, err := g.Run(context.Background(), &workers.Config{StopOnError: true})
+	require.ErrorIs(t, err, errBasic)
+	require.NotContains(t, results, "c")
+}
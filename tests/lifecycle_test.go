@@ -0,0 +1,630 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ygrebnov/workers"
+)
+
+func TestClose(t *testing.T) {
+	for _, stopOnError := range []bool{false, true} {
+		w, err := workers.New[string](
+			context.Background(),
+			&workers.Config{StartImmediately: true, StopOnError: stopOnError},
+		)
+		require.NoError(t, err)
+
+		for range 3 {
+			require.NoError(t, w.AddTask(basicTaskResult))
+		}
+
+		w.Close()
+
+		actual := make([]string, 0, 3)
+		for result := range w.GetResults() {
+			actual = append(actual, result)
+		}
+		require.ElementsMatch(t, generateExpected(3, basicTaskResult), actual)
+
+		_, ok := <-w.GetErrors()
+		require.False(t, ok)
+
+		require.ErrorIs(t, w.AddTask(basicTaskResult), workers.ErrInvalidState)
+
+		// Close is idempotent.
+		w.Close()
+	}
+}
+
+func TestStartImmediately_NoTasksBuffer(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{StartImmediately: true})
+	require.NoError(t, err)
+	defer w.Close()
+
+	// Tasks are added to the unbuffered queue without calling Start.
+	task := newTaskResult(5, 0)
+	require.NoError(t, w.AddTask(task))
+	require.Equal(t, generateExpected(1, task)[0], <-w.GetResults())
+}
+
+func TestClose_NotStarted(t *testing.T) {
+	w, err := workers.New[string](context.Background(), nil)
+	require.NoError(t, err)
+
+	w.Close()
+
+	_, ok := <-w.GetResults()
+	require.False(t, ok)
+	require.ErrorIs(t, w.AddTask(basicTaskResult), workers.ErrInvalidState)
+}
+
+func TestClose_ResultsBeforeErrors(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, ResultsBeforeErrors: true},
+	)
+	require.NoError(t, err)
+
+	const n = 10
+	for range n {
+		require.NoError(t, w.AddTask(newTaskResult(5, 0)))
+	}
+	require.NoError(t, w.AddTask(errorTaskResultError))
+
+	go w.Close()
+
+	var received int
+	results, errs := w.GetResults(), w.GetErrors()
+	for errs != nil {
+		select {
+		case _, ok := <-results:
+			if !ok {
+				results = nil
+				continue
+			}
+
+			// A slow consumer.
+			time.Sleep(10 * time.Millisecond)
+			received++
+
+		case _, ok := <-errs:
+			if !ok {
+				require.Equal(t, n, received)
+				errs = nil
+			}
+		}
+	}
+}
+
+func TestClose_ResultsBeforeErrors_Unread(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{
+			StartImmediately:     true,
+			ResultsBeforeErrors:  true,
+			CloseDropUnreadGrace: 50 * time.Millisecond,
+		},
+	)
+	require.NoError(t, err)
+
+	const n = 3
+	for range n {
+		require.NoError(t, w.AddTask(newTaskResult(5, 0)))
+	}
+	require.Eventually(t, func() bool {
+		return w.Stats().Completed == n
+	}, time.Second, 10*time.Millisecond)
+
+	// Nobody receives results, which are dropped after the grace period.
+	closed := make(chan struct{})
+	go func() {
+		w.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close is blocked by unread results")
+	}
+
+	require.Equal(t, uint64(n), w.DroppedResults())
+}
+
+func TestAddTask_NotStarted_ParentContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w, err := workers.New[string](ctx, &workers.Config{TasksBufferSize: 1})
+	require.NoError(t, err)
+
+	// Fill the tasks queue.
+	require.NoError(t, w.AddTask(basicTaskResult))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- w.AddTask(basicTaskResult)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err = <-errCh:
+		require.ErrorIs(t, err, workers.ErrInvalidState)
+		require.ErrorIs(t, err, context.Canceled)
+
+	case <-time.After(time.Second):
+		t.Fatal("AddTask is not unblocked by the parent context cancellation")
+	}
+}
+
+func TestClose_DropUnread(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, CloseDropUnreadGrace: 100 * time.Millisecond},
+	)
+	require.NoError(t, err)
+
+	// Nobody receives results, so tasks exceeding the results buffer capacity cannot send theirs.
+	const n, buffered = 1030, 1024
+	task := newTaskResult(1, 0)
+	for range n {
+		require.NoError(t, w.AddTask(task))
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		w.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close is blocked by unread results")
+	}
+
+	require.Equal(t, uint64(n-buffered), w.DroppedResults())
+	require.Len(t, w.GetResults(), buffered)
+}
+
+func TestClose_DropUnread_Received(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, CloseDropUnreadGrace: 200 * time.Millisecond},
+	)
+	require.NoError(t, err)
+
+	const n = 1030
+	task := newTaskResult(1, 0)
+	for range n {
+		require.NoError(t, w.AddTask(task))
+	}
+
+	go w.Close()
+
+	// Results received within the grace period after the buffer is full are not dropped.
+	time.Sleep(100 * time.Millisecond)
+
+	var received int
+	for range w.GetResults() {
+		received++
+	}
+
+	require.Equal(t, n, received)
+	require.Zero(t, w.DroppedResults())
+}
+
+func TestClose_ForceCancelGrace(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w, err := workers.New[string](
+		ctx,
+		&workers.Config{StartImmediately: true, ForceCancelGrace: 100 * time.Millisecond},
+	)
+	require.NoError(t, err)
+
+	// The task ignores the context cancellation.
+	started, release := make(chan struct{}), make(chan struct{})
+	require.NoError(t, w.AddTask(func(context.Context) string {
+		close(started)
+		<-release
+		return "late"
+	}))
+	<-started
+
+	cancel()
+
+	closed := make(chan struct{})
+	go func() {
+		w.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close is blocked by the task ignoring the context cancellation")
+	}
+
+	// The late result is dropped instead of being sent to the closed results channel.
+	close(release)
+	require.Eventually(t, func() bool {
+		return w.DroppedResults() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	_, ok := <-w.GetResults()
+	require.False(t, ok)
+}
+
+func TestNewWithCancel(t *testing.T) {
+	w, cancel, err := workers.NewWithCancel[string](context.Background(), nil)
+	require.NoError(t, err)
+
+	// Workers started with another context are stopped too.
+	w.Start(context.Background())
+
+	require.NoError(t, w.AddTask(basicTaskResult))
+	require.Equal(t, generateExpected(1, basicTaskResult)[0], <-w.GetResults())
+
+	cancel()
+
+	require.Eventually(t, func() bool {
+		return errors.Is(w.AddTask(basicTaskResult), workers.ErrInvalidState)
+	}, time.Second, 10*time.Millisecond)
+
+	// Channels stay open until Close.
+	select {
+	case <-w.GetResults():
+		t.Fatal("unexpected results channel receive")
+	default:
+	}
+
+	w.Close()
+
+	_, ok := <-w.GetResults()
+	require.False(t, ok)
+}
+
+func TestLifetime(t *testing.T) {
+	const lifetime = 100 * time.Millisecond
+
+	started := time.Now()
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, Lifetime: lifetime},
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, w.AddTask(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+
+	// The in-flight task is cancelled once the lifetime elapses, and not before.
+	select {
+	case err = <-w.GetErrors():
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.GreaterOrEqual(t, time.Since(started), lifetime)
+	case <-time.After(10 * time.Second):
+		t.Fatal("in-flight task is not cancelled")
+	}
+
+	// Tasks are rejected once the in-flight task has been cancelled.
+	require.Eventually(t, func() bool {
+		return errors.Is(w.AddTask(basicTaskResult), workers.ErrInvalidState)
+	}, 10*time.Second, 10*time.Millisecond)
+
+	w.Close()
+}
+
+func TestNew_CancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	w, err := workers.New[string](ctx, &workers.Config{StartImmediately: true})
+	require.ErrorIs(t, err, workers.ErrInvalidState)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Nil(t, w)
+}
+
+func TestErrorsFuture(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{StartImmediately: true})
+	require.NoError(t, err)
+
+	future := w.ErrorsFuture()
+	require.Same(t, future, w.ErrorsFuture())
+
+	errOther := errors.New("other")
+	require.NoError(t, w.AddTask(errorTaskResultError))
+	require.NoError(t, w.AddTask(newErrorTaskResultError(errOther)))
+	require.NoError(t, w.AddTask(basicTaskResult))
+
+	select {
+	case <-future.Done():
+		t.Fatal("future is resolved before Close")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	w.Close()
+
+	err = future.Get()
+	require.ErrorIs(t, err, errBasic)
+	require.ErrorIs(t, err, errOther)
+}
+
+func TestStartErr(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{StopOnError: true})
+	require.NoError(t, err)
+
+	require.NoError(t, w.StartErr(context.Background()))
+	require.ErrorIs(t, w.StartErr(context.Background()), workers.ErrAlreadyStarted)
+
+	w.Close()
+
+	w, err = workers.New[string](context.Background(), nil)
+	require.NoError(t, err)
+
+	w.Close()
+	require.ErrorIs(t, w.StartErr(context.Background()), workers.ErrInvalidState)
+}
+
+func TestCloseErr(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{StartImmediately: true})
+	require.NoError(t, err)
+
+	require.NoError(t, w.AddTask(errorTaskResultError))
+	require.ErrorIs(t, <-w.GetErrors(), errBasic)
+	require.NoError(t, w.CloseErr())
+
+	w, err = workers.New[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, StopOnError: true},
+	)
+	require.NoError(t, err)
+
+	// All tasks are dispatched before the first error stops workers.
+	gate := make(chan struct{})
+	for range 3 {
+		require.NoError(t, w.AddTask(func(context.Context) (string, error) {
+			<-gate
+			return "", errBasic
+		}))
+	}
+	require.Eventually(t, func() bool {
+		_, _, dequeued := w.QueueStats()
+		return dequeued == 3
+	}, time.Second, time.Millisecond)
+	close(gate)
+
+	err = w.CloseErr()
+	require.ErrorIs(t, err, workers.ErrErrorsDropped)
+	require.EqualError(t, err, "errors dropped: 2")
+}
+
+func TestCloseContext(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{StartImmediately: true})
+	require.NoError(t, err)
+
+	blocking := func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	require.NoError(t, w.AddTask(blocking))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, w.CloseContext(ctx), context.DeadlineExceeded)
+
+	// The task observes cancellation and closing completes.
+	require.ErrorIs(t, <-w.GetErrors(), context.Canceled)
+	require.NoError(t, w.CloseContext(context.Background()))
+
+	_, ok := <-w.GetResults()
+	require.False(t, ok)
+}
+
+func TestConsumeErrors(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{StartImmediately: true})
+	require.NoError(t, err)
+
+	var errs []error
+	done := w.ConsumeErrors(func(err error) {
+		errs = append(errs, err)
+	})
+
+	for range 3 {
+		require.NoError(t, w.AddTask(errorTaskResultError))
+	}
+
+	select {
+	case <-done:
+		t.Fatal("done is closed before Close")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	w.Close()
+	<-done
+
+	require.Len(t, errs, 3)
+	for _, err := range errs {
+		require.ErrorIs(t, err, errBasic)
+	}
+}
+
+func TestCloseCollect(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{StartImmediately: true})
+	require.NoError(t, err)
+
+	// More errors than the errors channel can buffer, with nobody reading them.
+	const n = 1100
+	for range n {
+		require.NoError(t, w.AddTask(errorTaskResultError))
+	}
+
+	err = w.CloseCollect()
+	require.ErrorIs(t, err, errBasic)
+	require.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), n)
+
+	require.NoError(t, w.CloseCollect())
+}
+
+func TestUndispatched(t *testing.T) {
+	t.Run("not started", func(t *testing.T) {
+		w, err := workers.New[string](context.Background(), &workers.Config{TasksBufferSize: 10})
+		require.NoError(t, err)
+
+		for range 3 {
+			require.NoError(t, w.AddTask(basicTaskResult))
+		}
+
+		require.Zero(t, w.Undispatched())
+
+		w.Close()
+		require.Equal(t, 3, w.Undispatched())
+	})
+
+	t.Run("started", func(t *testing.T) {
+		w, err := workers.New[string](
+			context.Background(),
+			&workers.Config{StartImmediately: true, MaxWorkers: 1, TasksBufferSize: 10},
+		)
+		require.NoError(t, err)
+
+		const n = 10
+		for range n {
+			require.NoError(t, w.AddTask(basicTaskResult))
+		}
+
+		w.Close()
+
+		results := 0
+		for range w.GetResults() {
+			results++
+		}
+
+		require.Equal(t, n, results+w.Undispatched())
+	})
+}
+
+func TestCloseWaitsForConsumers(t *testing.T) {
+	resultsDone, errorsDone := make(chan struct{}), make(chan struct{})
+	w, err := workers.New[string](context.Background(), &workers.Config{
+		StartImmediately: true,
+		ResultsDone:      resultsDone,
+		ErrorsDone:       errorsDone,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, w.AddTask(basicTaskResult))
+	require.NoError(t, w.AddTask(errorTaskResultError))
+
+	closed := make(chan struct{})
+	go func() {
+		w.Close()
+		close(closed)
+	}()
+
+	var results []string
+	for r := range w.GetResults() {
+		results = append(results, r)
+	}
+	close(resultsDone)
+
+	select {
+	case <-closed:
+		t.Fatal("Close returned before errors are consumed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	var errs []error
+	for e := range w.GetErrors() {
+		errs = append(errs, e)
+	}
+	close(errorsDone)
+
+	<-closed
+	require.Len(t, results, 1)
+	require.Len(t, errs, 1)
+}
+
+func TestDrain(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, TasksBufferSize: 4},
+	)
+	require.NoError(t, err)
+
+	for burst := range 2 {
+		for range 3 {
+			require.NoError(t, w.AddTask(basicTaskResult))
+		}
+
+		require.NoError(t, w.Drain(context.Background()), "burst %d", burst)
+		require.Len(t, w.GetResults(), 3)
+		for range 3 {
+			<-w.GetResults()
+		}
+	}
+
+	w.Close()
+}
+
+func TestDrain_ContextDone(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{StartImmediately: true})
+	require.NoError(t, err)
+
+	require.NoError(t, w.AddTask(longTaskResultError))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, w.Drain(ctx), context.DeadlineExceeded)
+}
+
+func TestDrain_Stopped(t *testing.T) {
+	w, cancel, err := workers.NewWithCancel[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, DynamicPoolMax: 1, TasksBufferSize: 2},
+	)
+	require.NoError(t, err)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	require.NoError(t, w.AddTask(func(context.Context) string {
+		close(started)
+		<-release
+		return "released"
+	}))
+	<-started
+	require.NoError(t, w.AddTask(basicTaskResult))
+
+	drained := make(chan error, 1)
+	go func() {
+		drained <- w.Drain(context.Background())
+	}()
+
+	// The queued task is never executed once workers stop, even after the in-flight one completes.
+	cancel()
+	time.Sleep(50 * time.Millisecond) // lets the dispatcher stop while the worker is busy
+	close(release)
+	require.ErrorIs(t, <-drained, workers.ErrInvalidState)
+
+	w.Close()
+}
+
+func TestDrain_Closed(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{TasksBufferSize: 2})
+	require.NoError(t, err)
+
+	require.NoError(t, w.AddTask(basicTaskResult))
+	w.Close()
+
+	// The queued task is never executed.
+	require.ErrorIs(t, w.Drain(context.Background()), workers.ErrInvalidState)
+}
@@ -126,3 +126,13 @@ var (
 	panicTaskResult      = newPanicTaskResult()
 	panicLongTaskError   = newPanicTaskError(700 * time.Millisecond)
 )
+
+// errorMessages returns the messages of errs, to compare errors of different types.
+func errorMessages(errs []error) []string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+
+	return messages
+}
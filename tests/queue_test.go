@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ygrebnov/workers"
+)
+
+// sliceQueue is a slice-backed workers.Queue.
+type sliceQueue struct {
+	mu       sync.Mutex
+	items    []interface{}
+	notify   chan struct{}
+	enqueued int
+}
+
+func newSliceQueue() *sliceQueue {
+	return &sliceQueue{notify: make(chan struct{}, 1)}
+}
+
+func (q *sliceQueue) Enqueue(_ context.Context, t interface{}) error {
+	q.mu.Lock()
+	q.items = append(q.items, t)
+	q.enqueued++
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (q *sliceQueue) Dequeue(ctx context.Context) (interface{}, error) {
+	for {
+		q.mu.Lock()
+		if len(q.items) > 0 {
+			t := q.items[0]
+			q.items = q.items[1:]
+			q.mu.Unlock()
+			return t, nil
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.notify:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (q *sliceQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+func TestQueue(t *testing.T) {
+	q := newSliceQueue()
+
+	w, err := workers.New[string](context.Background(), &workers.Config{Queue: q})
+	require.NoError(t, err)
+
+	const n = 5
+	for range n {
+		require.NoError(t, w.AddTask(basicTaskResult))
+	}
+
+	depth, _, _ := w.QueueStats()
+	require.Equal(t, n, depth)
+
+	w.Start(context.Background())
+
+	actual := make([]string, 0, n)
+	for range n {
+		actual = append(actual, <-w.GetResults())
+	}
+	require.ElementsMatch(t, generateExpected(n, basicTaskResult), actual)
+	require.Equal(t, n, q.enqueued)
+	require.Equal(t, 0, q.Len())
+
+	w.Close()
+}
+
+// corruptingQueue is a sliceQueue which replaces the dequeued tasks with another value.
+type corruptingQueue struct {
+	*sliceQueue
+}
+
+func (q corruptingQueue) Dequeue(ctx context.Context) (interface{}, error) {
+	if _, err := q.sliceQueue.Dequeue(ctx); err != nil {
+		return nil, err
+	}
+
+	return "restored", nil
+}
+
+func TestQueue_InvalidValue(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, Queue: corruptingQueue{newSliceQueue()}},
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, w.AddTask(basicTaskResult))
+	require.ErrorIs(t, <-w.GetErrors(), workers.ErrInvalidConfig)
+
+	w.Close()
+}
+
+func TestPriority_AddedWhileBusy(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, Priority: true, DynamicPoolMax: 1},
+	)
+	require.NoError(t, err)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	require.NoError(t, w.AddTask(func(context.Context) (string, error) {
+		close(started)
+		<-release
+		return "busy", nil
+	}))
+	<-started
+
+	// The task added first must not be held while the worker is busy,
+	// so that the task added later with a higher priority is dispatched before it.
+	require.NoError(t, w.AddTaskPriority(newTaskResult(1, 0), 2))
+	time.Sleep(10 * time.Millisecond) // gives the task time to be dequeued, which it must not be
+	require.NoError(t, w.AddTaskPriority(newTaskResult(2, 0), 1))
+	close(release)
+
+	actual := make([]string, 0, 3)
+	for range 3 {
+		actual = append(actual, <-w.GetResults())
+	}
+	require.Equal(t, []string{"busy", "ss ss", "s"}, actual)
+
+	w.Close()
+}
+
+func TestPriority(t *testing.T) {
+	// A single worker executes tasks one by one in the order of dispatching.
+	w, err := workers.New[string](context.Background(), &workers.Config{Priority: true, DynamicPoolMax: 1})
+	require.NoError(t, err)
+
+	require.NoError(t, w.AddTaskPriority(newTaskResult(1, 0), 2))
+	require.NoError(t, w.AddTaskPriority(newTaskResult(2, 0), 1))
+	require.NoError(t, w.AddTask(newTaskResult(3, 0)))
+	require.NoError(t, w.AddTaskPriority(newTaskResult(4, 0), 1))
+
+	depth, _, _ := w.QueueStats()
+	require.Equal(t, 4, depth)
+
+	w.Start(context.Background())
+
+	actual := make([]string, 0, 4)
+	for range 4 {
+		actual = append(actual, <-w.GetResults())
+	}
+	require.Equal(t, []string{"sss sss sss", "ss ss", "ssss ssss ssss ssss", "s"}, actual)
+
+	w.Close()
+
+	_, err = workers.New[string](
+		context.Background(),
+		&workers.Config{Priority: true, Queue: newSliceQueue()},
+	)
+	require.ErrorIs(t, err, workers.ErrInvalidConfig)
+
+	w, err = workers.New[string](context.Background(), nil)
+	require.NoError(t, err)
+	require.ErrorIs(t, w.AddTaskPriority(basicTaskResult, 1), workers.ErrInvalidConfig)
+}
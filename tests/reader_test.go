@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ygrebnov/workers"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errBasic
+}
+
+func TestTasksFromReader(t *testing.T) {
+	w, err := workers.New[struct{}](context.Background(), &workers.Config{StartImmediately: true})
+	require.NoError(t, err)
+
+	var (
+		mu    sync.Mutex
+		lines []string
+	)
+	fn := func(_ context.Context, line string) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		lines = append(lines, line)
+		return nil
+	}
+
+	r := io.MultiReader(strings.NewReader("a\nb\nc\n"), failingReader{})
+	for task := range workers.TasksFromReader(context.Background(), r, fn) {
+		require.NoError(t, w.AddTask(task))
+	}
+
+	w.Close()
+	require.ElementsMatch(t, []string{"a", "b", "c"}, lines)
+	require.ErrorIs(t, <-w.GetErrors(), errBasic)
+}
+
+func TestTasksFromReader_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	tasks := workers.TasksFromReader(ctx, strings.NewReader("a\nb\nc\n"), func(context.Context, string) error {
+		return errors.New("unexpected")
+	})
+
+	<-tasks
+	cancel()
+
+	// At most the task being sent on cancellation is received.
+	n := 0
+	for range tasks {
+		n++
+	}
+	require.LessOrEqual(t, n, 1)
+}
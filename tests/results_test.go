@@ -0,0 +1,427 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ygrebnov/workers"
+)
+
+func TestResultTTL(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, ResultTTL: 100 * time.Millisecond},
+	)
+	require.NoError(t, err)
+
+	task := newTaskResult(5, 0)
+	for range 3 {
+		require.NoError(t, w.AddTask(task))
+	}
+
+	// Do not receive results for longer than the TTL.
+	time.Sleep(300 * time.Millisecond)
+
+	select {
+	case r := <-w.GetResults():
+		t.Fatalf("unexpected stale result: %s", r)
+	default:
+	}
+	require.Equal(t, uint64(3), w.DroppedResults())
+
+	// Fresh results are still delivered.
+	require.NoError(t, w.AddTask(task))
+	require.Equal(t, generateExpected(1, task)[0], <-w.GetResults())
+
+	w.Close()
+	_, ok := <-w.GetResults()
+	require.False(t, ok)
+}
+
+func TestSerializedResults(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{MaxWorkers: 4, StartImmediately: true, SerializedResults: true},
+	)
+	require.NoError(t, err)
+
+	const n = 100
+	task := newTaskResult(5, time.Millisecond)
+	for range n {
+		require.NoError(t, w.AddTask(task))
+	}
+
+	w.Close()
+
+	actual := make([]string, 0, n)
+	for result := range w.GetResults() {
+		actual = append(actual, result)
+	}
+	require.ElementsMatch(t, generateExpected(n, task), actual)
+}
+
+func TestResultKey(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{
+			StartImmediately: true,
+			ResultKey:        func(r string) interface{} { return r[:1] },
+		},
+	)
+	require.NoError(t, err)
+
+	for _, r := range []string{"a1", "b1", "a2", "c1", "b2", "a3"} {
+		require.NoError(t, w.AddTask(func(context.Context) string { return r }))
+	}
+
+	w.Close()
+
+	keys := make([]string, 0, 3)
+	for result := range w.GetResults() {
+		keys = append(keys, result[:1])
+	}
+	require.ElementsMatch(t, []string{"a", "b", "c"}, keys)
+	require.Equal(t, uint64(3), w.DroppedResults())
+}
+
+func TestResultKey_NotComparable(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{
+			StartImmediately:    true,
+			StopOnError:         true,
+			ResultsBeforeErrors: true,
+			ResultKey:           func(r string) interface{} { return []string{r} },
+		},
+	)
+	require.NoError(t, err)
+
+	for range 2 {
+		require.NoError(t, w.AddTask(newTaskResult(5, 0)))
+	}
+
+	expected := generateExpected(1, newTaskResult(5, 0))[0]
+	require.Equal(t, expected, <-w.GetResults())
+	require.Equal(t, expected, <-w.GetResults())
+
+	w.Close()
+
+	_, ok := <-w.GetErrors()
+	require.False(t, ok)
+
+	stats := w.Stats()
+	require.Equal(t, uint64(2), stats.InvalidResultKeys)
+	require.Zero(t, stats.Failed)
+}
+
+func TestResultKey_InvalidType(t *testing.T) {
+	_, err := workers.New[string](
+		context.Background(),
+		&workers.Config{ResultKey: func(r int) interface{} { return r }},
+	)
+	require.ErrorIs(t, err, workers.ErrInvalidConfig)
+}
+
+func TestAccumulate(t *testing.T) {
+	w, err := workers.New[int](context.Background(), &workers.Config{StartImmediately: true})
+	require.NoError(t, err)
+
+	sum := workers.Accumulate(w, 0, func(acc, r int) int { return acc + r })
+
+	for i := 1; i <= 100; i++ {
+		require.NoError(t, w.AddTask(func(context.Context) (int, error) { return i, nil }))
+	}
+
+	w.Close()
+	require.Equal(t, 5050, sum.Get())
+}
+
+func TestSinks(t *testing.T) {
+	for _, sinksOnly := range []bool{false, true} {
+		t.Run(fmt.Sprintf("sinks only %t", sinksOnly), func(t *testing.T) {
+			var (
+				results []string
+				errs    []error
+			)
+
+			w, err := workers.New[string](context.Background(), &workers.Config{
+				StartImmediately: true,
+				ResultSink: func(r string) {
+					time.Sleep(10 * time.Millisecond)
+					results = append(results, r)
+				},
+				ErrorSink: func(err error) {
+					time.Sleep(10 * time.Millisecond)
+					errs = append(errs, err)
+				},
+				SinksOnly: sinksOnly,
+			})
+			require.NoError(t, err)
+
+			for range 3 {
+				require.NoError(t, w.AddTask(newTaskResult(5, 0)))
+				require.NoError(t, w.AddTask(errorTaskResultError))
+			}
+
+			w.Close()
+
+			// Close waits for the sinks to handle everything.
+			require.Len(t, results, 3)
+			require.Len(t, errs, 3)
+
+			if sinksOnly {
+				require.Empty(t, w.GetResults())
+				require.Empty(t, w.GetErrors())
+			} else {
+				require.Len(t, w.GetResults(), 3)
+				require.Len(t, w.GetErrors(), 3)
+			}
+		})
+	}
+
+	_, err := workers.New[string](context.Background(), &workers.Config{ResultSink: func(int) {}})
+	require.ErrorIs(t, err, workers.ErrInvalidConfig)
+}
+
+func TestNamedOutputs(t *testing.T) {
+	w, err := workers.New[int](
+		context.Background(),
+		&workers.Config{StartImmediately: true, NamedOutputs: []string{"a", "b"}},
+	)
+	require.NoError(t, err)
+	require.Nil(t, w.Output("c"))
+
+	for i := range 6 {
+		name := "a"
+		if i%2 == 1 {
+			name = "b"
+		}
+		require.NoError(t, w.AddTask(workers.TaskToOutput[int](name, newTaskValue(i, 0))))
+	}
+	require.NoError(t, w.AddTask(newTaskValue(6, 0)))
+	require.ErrorIs(t, w.AddTask(workers.TaskToOutput[int]("c", newTaskValue(7, 0))), workers.ErrInvalidConfig)
+
+	w.Close()
+
+	collect := func(ch chan int) []int {
+		var values []int
+		for v := range ch {
+			values = append(values, v)
+		}
+		return values
+	}
+	require.ElementsMatch(t, []int{0, 2, 4}, collect(w.Output("a")))
+	require.ElementsMatch(t, []int{1, 3, 5}, collect(w.Output("b")))
+	require.Equal(t, []int{6}, collect(w.GetResults()))
+
+	_, err = workers.New[int](context.Background(), &workers.Config{NamedOutputs: []string{"a", "a"}})
+	require.ErrorIs(t, err, workers.ErrInvalidConfig)
+}
+
+func TestResultEncoder(t *testing.T) {
+	type item struct {
+		N int `json:"n"`
+	}
+
+	var out bytes.Buffer
+	w, err := workers.New[item](context.Background(), &workers.Config{
+		StartImmediately: true,
+		ResultEncoder: func(r item) ([]byte, error) {
+			if r.N < 0 {
+				return nil, errBasic
+			}
+			return json.Marshal(r)
+		},
+		EncodedOutput: &out,
+	})
+	require.NoError(t, err)
+
+	for i := -1; i < 5; i++ {
+		require.NoError(t, w.AddTask(func(context.Context) item { return item{N: i} }))
+	}
+
+	w.Close()
+	require.Len(t, w.GetResults(), 6)
+	require.ErrorIs(t, <-w.GetErrors(), errBasic)
+
+	var decoded []int
+	for out.Len() > 0 {
+		size := binary.BigEndian.Uint32(out.Next(4))
+
+		var r item
+		require.NoError(t, json.Unmarshal(out.Next(int(size)), &r))
+		decoded = append(decoded, r.N)
+	}
+	require.ElementsMatch(t, []int{0, 1, 2, 3, 4}, decoded)
+
+	_, err = workers.New[item](context.Background(), &workers.Config{ResultEncoder: json.Marshal})
+	require.ErrorIs(t, err, workers.ErrInvalidConfig)
+}
+
+func TestReliableResults(t *testing.T) {
+	w, err := workers.New[int](
+		context.Background(),
+		&workers.Config{StartImmediately: true, ReliableResults: true, SerializedResults: true},
+	)
+	require.NoError(t, err)
+
+	const n = 100
+	go func() {
+		for i := range n {
+			_ = w.AddTask(newTaskValue(i, 0))
+		}
+	}()
+
+	// The slow reader receives every result exactly once.
+	seen := make(map[int]int, n)
+	for range n {
+		seen[<-w.GetResults()]++
+		time.Sleep(time.Millisecond)
+	}
+	w.Close()
+
+	require.Len(t, seen, n)
+	for _, count := range seen {
+		require.Equal(t, 1, count)
+	}
+	require.Zero(t, w.DroppedResults())
+
+	for _, config := range []*workers.Config{
+		{ReliableResults: true, ResultTTL: time.Second},
+		{ReliableResults: true, CloseDropUnreadGrace: time.Second},
+		{ReliableResults: true, ForceCancelGrace: time.Second},
+	} {
+		_, err = workers.New[int](context.Background(), config)
+		require.ErrorIs(t, err, workers.ErrInvalidConfig)
+	}
+}
+
+func TestResultsIterator(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{StartImmediately: true})
+	require.NoError(t, err)
+
+	const n = 5
+	for range n {
+		require.NoError(t, w.AddTask(newTaskResult(5, 0)))
+		require.NoError(t, w.AddTask(errorTaskResultError))
+	}
+
+	go func() {
+		for w.Stats().Completed < 2*n {
+			time.Sleep(10 * time.Millisecond)
+		}
+		w.Close()
+	}()
+
+	var (
+		results []string
+		errs    []error
+	)
+	w.Results()(func(r string, err error) bool {
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			results = append(results, r)
+		}
+
+		return true
+	})
+
+	require.Equal(t, generateExpected(n, newTaskResult(5, 0)), results)
+	require.Len(t, errs, n)
+	for _, err = range errs {
+		require.ErrorIs(t, err, errBasic)
+	}
+
+	// Iteration stops once yield returns false.
+	w2, err := workers.New[string](context.Background(), &workers.Config{StartImmediately: true})
+	require.NoError(t, err)
+	defer w2.Close()
+
+	require.NoError(t, w2.AddTask(newTaskResult(5, 0)))
+	require.NoError(t, w2.AddTask(newTaskResult(5, 0)))
+
+	var yielded int
+	w2.Results()(func(string, error) bool {
+		yielded++
+		return false
+	})
+	require.Equal(t, 1, yielded)
+}
+
+func TestEndMarker(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, EndMarker: func() string { return "EOF" }},
+	)
+	require.NoError(t, err)
+
+	const n = 5
+	task := newTaskResult(5, 0)
+	for range n {
+		require.NoError(t, w.AddTask(task))
+	}
+
+	go func() {
+		for w.Stats().Completed < n {
+			time.Sleep(10 * time.Millisecond)
+		}
+		w.Close()
+	}()
+
+	var received []string
+	for r := range w.GetResults() {
+		received = append(received, r)
+	}
+
+	require.Equal(t, append(generateExpected(n, task), "EOF"), received)
+
+	_, err = workers.New[string](context.Background(), &workers.Config{EndMarker: func() int { return 0 }})
+	require.ErrorIs(t, err, workers.ErrInvalidConfig)
+}
+
+func TestResultTransform(t *testing.T) {
+	var calls atomic.Int32
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{
+			StartImmediately: true,
+			ResultTransform: func(r string) string {
+				calls.Add(1)
+				return strings.ToUpper(r)
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	const n = 3
+	task := newTaskResult(5, 0)
+	for range n {
+		require.NoError(t, w.AddTask(task))
+		require.NoError(t, w.AddTask(newTaskError(5, 0)))
+	}
+
+	for _, expected := range generateExpected(n, task) {
+		require.Equal(t, strings.ToUpper(expected), <-w.GetResults())
+	}
+
+	require.Eventually(t, func() bool {
+		return w.Stats().Completed == 2*n
+	}, time.Second, 10*time.Millisecond)
+	w.Close()
+
+	// Tasks returning only an error have no results to transform.
+	require.Equal(t, int32(n), calls.Load())
+
+	_, err = workers.New[string](context.Background(), &workers.Config{ResultTransform: func(int) int { return 0 }})
+	require.ErrorIs(t, err, workers.ErrInvalidConfig)
+}
@@ -0,0 +1,216 @@
+package tests
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ygrebnov/workers"
+)
+
+func newTaskValue(v int, wait time.Duration) func(context.Context) (int, error) {
+	return func(context.Context) (int, error) {
+		time.Sleep(wait)
+		return v, nil
+	}
+}
+
+func TestRunAllSorted(t *testing.T) {
+	tasks := []interface{}{
+		newTaskValue(3, 10*time.Millisecond),
+		newTaskValue(1, 50*time.Millisecond),
+		newTaskValue(4, 0),
+		newTaskValue(2, 30*time.Millisecond),
+	}
+
+	results, err := workers.RunAllSorted(
+		context.Background(),
+		tasks,
+		func(a, b int) bool { return a > b },
+		nil,
+	)
+	require.NoError(t, err)
+	require.Equal(t, []int{4, 3, 2, 1}, results)
+}
+
+func TestRunAllSorted_Errors(t *testing.T) {
+	tasks := []interface{}{
+		newTaskValue(2, 0),
+		func(context.Context) (int, error) { return 0, errBasic },
+		newTaskValue(1, 0),
+	}
+
+	results, err := workers.RunAllSorted(
+		context.Background(),
+		tasks,
+		func(a, b int) bool { return a < b },
+		&workers.Config{MaxWorkers: 2},
+	)
+	require.ErrorIs(t, err, errBasic)
+	require.Equal(t, []int{1, 2}, results)
+}
+
+func TestRunAllSorted_StopOnError(t *testing.T) {
+	tasks := []interface{}{
+		func(context.Context) (int, error) { return 0, errBasic },
+	}
+	for range 10 {
+		tasks = append(tasks, newTaskValue(1, 0))
+	}
+
+	// The rate limit keeps the tasks from being added until the error stops workers.
+	_, err := workers.RunAllSorted(
+		context.Background(),
+		tasks,
+		func(a, b int) bool { return a < b },
+		&workers.Config{StopOnError: true, RateLimit: 10},
+	)
+	require.ErrorIs(t, err, errBasic)
+	require.NotErrorIs(t, err, workers.ErrInvalidState)
+}
+
+func TestRunAllHandle(t *testing.T) {
+	blocking := func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	tasks := []interface{}{newTaskValue(1, 0), newTaskValue(1, 0), blocking, blocking}
+
+	h, err := workers.RunAllHandle[int](context.Background(), tasks, nil)
+	require.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	h.Cancel()
+
+	results, err := h.Results()
+	require.Equal(t, []int{1, 1}, results)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// A batch completing without being cancelled has no cancellation error.
+	h, err = workers.RunAllHandle[int](context.Background(), tasks[:2], nil)
+	require.NoError(t, err)
+
+	results, err = h.Results()
+	require.Equal(t, []int{1, 1}, results)
+	require.NoError(t, err)
+	h.Cancel()
+}
+
+func TestRunAll_NoTasks(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+
+	results, err := workers.RunAllSorted(context.Background(), nil, func(a, b int) bool { return a < b }, nil)
+	require.NoError(t, err)
+	require.Empty(t, results)
+
+	outcomes, err := workers.RunAllIndexed[int](context.Background(), nil, nil)
+	require.NoError(t, err)
+	require.Empty(t, outcomes)
+
+	h, err := workers.RunAllHandle[int](context.Background(), nil, nil)
+	require.NoError(t, err)
+	results, err = h.Results()
+	require.NoError(t, err)
+	require.Empty(t, results)
+
+	require.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+
+	// The config is validated nevertheless.
+	invalid := &workers.Config{MaxWorkers: 1, DynamicPoolMax: 1}
+
+	_, err = workers.RunAllSorted(context.Background(), nil, func(a, b int) bool { return a < b }, invalid)
+	require.ErrorIs(t, err, workers.ErrInvalidConfig)
+
+	_, err = workers.RunAllIndexed[int](context.Background(), nil, invalid)
+	require.ErrorIs(t, err, workers.ErrInvalidConfig)
+
+	_, err = workers.RunAllHandle[int](context.Background(), nil, invalid)
+	require.ErrorIs(t, err, workers.ErrInvalidConfig)
+}
+
+func TestRunAllIndexed(t *testing.T) {
+	tasks := []interface{}{
+		newTaskValue(3, 30*time.Millisecond),
+		func(context.Context) (int, error) { return 0, errBasic },
+		newTaskValue(1, 0),
+		func(context.Context) int { panic("panic") },
+	}
+
+	outcomes, err := workers.RunAllIndexed[int](context.Background(), tasks, nil)
+	require.ErrorIs(t, err, errBasic)
+	require.ErrorIs(t, err, workers.ErrTaskPanicked)
+	require.Len(t, outcomes, len(tasks))
+
+	for i, o := range outcomes {
+		require.Equal(t, i, o.Index)
+	}
+	require.Equal(t, 3, outcomes[0].Value)
+	require.NoError(t, outcomes[0].Err)
+	require.ErrorIs(t, outcomes[1].Err, errBasic)
+	require.Equal(t, 1, outcomes[2].Value)
+	require.ErrorIs(t, outcomes[3].Err, workers.ErrTaskPanicked)
+}
+
+func TestRunAllIndexed_TaskBehaviours(t *testing.T) {
+	var (
+		panics  atomic.Int32
+		results atomic.Int32
+	)
+	tasks := []interface{}{
+		func(context.Context) int { panic("panic") },
+		func(context.Context) error { return nil },
+		workers.TaskWithPanicDefault(7, func(context.Context) int { panic("panic") }),
+	}
+
+	outcomes, err := workers.RunAllIndexed[int](
+		context.Background(),
+		tasks,
+		&workers.Config{
+			Name:          "indexed",
+			RetryAttempts: 2,
+			PanicHandler:  func(interface{}) { panics.Add(1) },
+			ResultSink:    func(int) { results.Add(1) },
+			Middleware: []interface{}{
+				func(next func(context.Context) (int, error)) func(context.Context) (int, error) {
+					return next
+				},
+			},
+		},
+	)
+	require.Error(t, err)
+
+	// The panic is handled, not retried, and reported with the workers name.
+	var panicErr *workers.PanicError
+	require.ErrorAs(t, outcomes[0].Err, &panicErr)
+	require.Contains(t, outcomes[0].Err.Error(), "[indexed]")
+	require.Equal(t, int32(2), panics.Load())
+
+	// The error-only task emits no result, and the panic default is found behind the middleware.
+	require.NoError(t, outcomes[1].Err)
+	require.NoError(t, outcomes[2].Err)
+	require.Equal(t, 7, outcomes[2].Value)
+	require.Equal(t, int32(1), results.Load())
+}
+
+func TestRunAllIndexed_NotStarted(t *testing.T) {
+	tasks := []interface{}{
+		func(context.Context) (int, error) { return 0, errBasic },
+	}
+	for range 10 {
+		tasks = append(tasks, newTaskValue(1, 0))
+	}
+
+	// The rate limit keeps the tasks in the queue until the error stops workers.
+	outcomes, err := workers.RunAllIndexed[int](
+		context.Background(),
+		tasks,
+		&workers.Config{StopOnError: true, RateLimit: 10},
+	)
+	require.ErrorIs(t, err, errBasic)
+	require.ErrorIs(t, outcomes[0].Err, errBasic)
+	require.ErrorIs(t, outcomes[len(outcomes)-1].Err, workers.ErrNotStarted)
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ygrebnov/workers"
+)
+
+func TestShutdownSignals(t *testing.T) {
+	signals := make(chan os.Signal, 1)
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, ShutdownChannel: signals},
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, w.AddTask(basicTaskResult))
+
+	signals <- syscall.SIGTERM
+
+	// Workers are closed after the dispatched task completes.
+	require.Equal(t, generateExpected(1, basicTaskResult)[0], <-w.GetResults())
+
+	select {
+	case _, ok := <-w.GetResults():
+		require.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("workers are not closed on the signal")
+	}
+}
+
+func TestShutdownGrace(t *testing.T) {
+	signals := make(chan os.Signal, 1)
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{
+			StartImmediately: true,
+			ShutdownChannel:  signals,
+			ShutdownGrace:    50 * time.Millisecond,
+		},
+	)
+	require.NoError(t, err)
+
+	// The task runs until its context is cancelled once the grace period elapses.
+	started := make(chan struct{})
+	require.NoError(t, w.AddTask(func(ctx context.Context) (string, error) {
+		close(started)
+		<-ctx.Done()
+		return "", ctx.Err()
+	}))
+	<-started
+
+	signals <- syscall.SIGTERM
+
+	select {
+	case err = <-w.GetErrors():
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("tasks are not cancelled after the grace period")
+	}
+
+	select {
+	case _, ok := <-w.GetResults():
+		require.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("workers are not closed on the signal")
+	}
+}
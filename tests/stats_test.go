@@ -0,0 +1,204 @@
+package tests
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ygrebnov/workers"
+)
+
+func TestHeartbeat(t *testing.T) {
+	ticks := make(chan time.Time)
+	beats := make(chan workers.Stats)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w, err := workers.New[string](ctx, &workers.Config{
+		MaxWorkers:       2,
+		StartImmediately: true,
+		HeartbeatTicks:   ticks,
+		OnHeartbeat: func(s workers.Stats) {
+			beats <- s
+		},
+	})
+	require.NoError(t, err)
+
+	beat := func() workers.Stats {
+		ticks <- time.Now()
+		return <-beats
+	}
+
+	// Heartbeats are fired while idle.
+	require.Zero(t, beat().Completed)
+
+	tasks := []interface{}{basicTaskResult, basicTaskResult, basicTaskResult}
+	for _, task := range tasks {
+		require.NoError(t, w.AddTask(task))
+	}
+
+	var last uint64
+	for range tasks {
+		<-w.GetResults()
+
+		s := beat()
+		require.GreaterOrEqual(t, s.Completed, last)
+		last = s.Completed
+	}
+
+	require.Eventually(t, func() bool {
+		return beat().Completed == uint64(len(tasks))
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestHeartbeat_Interval(t *testing.T) {
+	beats := make(chan workers.Stats, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := workers.New[string](ctx, &workers.Config{
+		StartImmediately:  true,
+		HeartbeatInterval: 10 * time.Millisecond,
+		OnHeartbeat: func(s workers.Stats) {
+			select {
+			case beats <- s:
+			default:
+			}
+		},
+	})
+	require.NoError(t, err)
+
+	select {
+	case <-beats:
+	case <-time.After(time.Second):
+		t.Fatal("no heartbeat is fired")
+	}
+}
+
+func TestHeartbeat_NoCallback(t *testing.T) {
+	_, err := workers.New[string](context.Background(), &workers.Config{HeartbeatInterval: time.Second})
+	require.ErrorIs(t, err, workers.ErrInvalidConfig)
+	_, err = workers.New[string](
+		context.Background(),
+		&workers.Config{HeartbeatInterval: -time.Second, OnHeartbeat: func(workers.Stats) {}},
+	)
+	require.ErrorIs(t, err, workers.ErrInvalidConfig)
+}
+
+func TestQueueStats(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{TasksBufferSize: 8})
+	require.NoError(t, err)
+
+	const n = 5
+	for range n {
+		require.NoError(t, w.AddTask(basicTaskResult))
+	}
+
+	depth, enqueued, dequeued := w.QueueStats()
+	require.Equal(t, n, depth)
+	require.Equal(t, uint64(n), enqueued)
+	require.Equal(t, uint64(0), dequeued)
+
+	w.Start(context.Background())
+	for range n {
+		<-w.GetResults()
+	}
+
+	depth, enqueued, dequeued = w.QueueStats()
+	require.Equal(t, 0, depth)
+	require.Equal(t, uint64(n), enqueued)
+	require.Equal(t, enqueued, dequeued)
+}
+
+func TestBusyIdleWorkers(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{MaxWorkers: 4, StartImmediately: true})
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	blocking := func(context.Context) string {
+		<-release
+		return ""
+	}
+
+	require.NoError(t, w.AddTask(blocking))
+	require.NoError(t, w.AddTask(blocking))
+
+	require.Eventually(t, func() bool { return w.BusyWorkers() == 2 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, 2, w.IdleWorkers())
+
+	close(release)
+	w.Close()
+
+	require.Equal(t, 0, w.BusyWorkers())
+	require.Equal(t, 4, w.IdleWorkers())
+}
+
+func TestStats(t *testing.T) {
+	w, err := workers.New[string](context.Background(), &workers.Config{StartImmediately: true})
+	require.NoError(t, err)
+
+	require.Equal(t, workers.Stats{}, w.Stats())
+
+	require.NoError(t, w.AddTask(basicTaskResult))
+	require.NoError(t, w.AddTask(errorTaskResultError))
+	require.NoError(t, w.AddTask(panicTaskResult))
+
+	<-w.GetResults()
+	<-w.GetErrors()
+	<-w.GetErrors()
+
+	require.Eventually(t, func() bool {
+		return w.Stats().Completed == 3
+	}, time.Second, time.Millisecond)
+
+	s := w.Stats()
+	require.Equal(t, uint64(3), s.Enqueued)
+	require.Equal(t, uint64(2), s.Failed)
+	require.Zero(t, s.InFlight)
+	require.Zero(t, s.QueueLen)
+	require.Zero(t, s.ActiveWorkers)
+
+	w.Close()
+}
+
+func TestDynamicPoolMax(t *testing.T) {
+	w, err := workers.New[string](
+		context.Background(),
+		&workers.Config{StartImmediately: true, TasksBufferSize: 20, DynamicPoolMax: 3},
+	)
+	require.NoError(t, err)
+
+	var running, maxRunning atomic.Int32
+	task := func(context.Context) string {
+		n := running.Add(1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+		return ""
+	}
+
+	for range 20 {
+		require.NoError(t, w.AddTask(task))
+	}
+
+	for range 20 {
+		<-w.GetResults()
+	}
+	require.Equal(t, int32(3), maxRunning.Load())
+
+	w.Close()
+
+	_, err = workers.New[string](context.Background(), &workers.Config{MaxWorkers: 2, DynamicPoolMax: 3})
+	require.ErrorIs(t, err, workers.ErrInvalidConfig)
+}
@@ -2,33 +2,250 @@ package workers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
 )
 
 type worker[R interface{}] struct {
 	results chan R
 	errors  chan error
+	owner   *workers[R]
+
+	// state is created by WorkerInit.
+	initMu      sync.Mutex
+	initialized bool
+	state       interface{}
 }
 
-func newWorker[R interface{}](results chan R, errors chan error) *worker[R] {
-	return &worker[R]{results: results, errors: errors}
+func newWorker[R interface{}](results chan R, errors chan error, owner *workers[R]) *worker[R] {
+	return &worker[R]{results: results, errors: errors, owner: owner}
 }
 
 func (w *worker[R]) execute(ctx context.Context, t task[R]) {
+	var (
+		result R
+		err    error
+	)
+
+	if n, ok := unwrapTo[*taskNotify[R]](t); ok {
+		defer func() {
+			n.notify(result, err)
+		}()
+	}
+
+	if err = w.init(); err != nil {
+		err = w.fail(fmt.Errorf("worker initialization failed: %w", err))
+		return
+	}
+
 	defer func() {
 		if ePanic := recover(); ePanic != nil {
-			w.errors <- fmt.Errorf("task execution panicked: %v", ePanic)
+			w.handlePanic(ePanic)
+
+			if pd, ok := unwrapTo[*taskPanicDefault[R]](t); ok {
+				result, err = pd.def, nil
+				w.sendResult(t, result)
+				return
+			}
+
+			result, err = *(new(R)), w.fail(&PanicError{value: ePanic, stack: debug.Stack()})
 		}
 	}()
 
-	result, err := t.execute(ctx)
+	result, err = w.executeOnce(ctx, t)
+
+	// Cancelled tasks would fail the same way again.
+	for attempt := uint(1); err != nil && !errors.Is(err, ErrTaskCancelled) &&
+		attempt <= w.owner.config.RetryAttempts; attempt++ {
+		if !w.backoff(ctx, attempt) {
+			break
+		}
+
+		w.owner.retries.Add(1)
+		result, err = w.executeOnce(ctx, t)
+	}
 
 	if err != nil {
-		w.errors <- err
+		if _, ok := baseTask(t).(*taskResultError[R]); ok && w.owner.config.EmitResultOnError {
+			w.sendResult(t, result)
+		}
+
+		err = w.fail(err)
+		return
+	}
+
+	if emitsResult(t) {
+		w.sendResult(t, result)
+	}
+}
+
+// handlePanic calls the configured panic handler, if any, recovering from its panics.
+func (w *worker[R]) handlePanic(recovered interface{}) {
+	if w.owner.config.PanicHandler == nil {
+		return
+	}
+
+	defer func() {
+		_ = recover()
+	}()
+
+	w.owner.config.PanicHandler(recovered)
+}
+
+// executeOnce makes a single attempt to execute the task.
+func (w *worker[R]) executeOnce(ctx context.Context, t task[R]) (R, error) {
+	if w.owner.config.TaskTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.owner.config.TaskTimeout)
+		defer cancel()
+	}
+
+	if w.owner.config.WorkerInit != nil {
+		ctx = context.WithValue(ctx, workerStateKey{}, w.state)
+	}
+
+	if w.owner.config.ContextValues != nil {
+		ctx = w.owner.config.ContextValues(ctx)
+	}
+
+	result, err := t.execute(withTaskLocals(ctx))
+	return result, deadlineError(ctx, err)
+}
+
+// backoff waits before the given retry attempt. It returns false if the task must not be retried
+// because the context is cancelled.
+func (w *worker[R]) backoff(ctx context.Context, attempt uint) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if w.owner.config.RetryBackoff == nil {
+		return true
+	}
+
+	timer := time.NewTimer(w.owner.config.RetryBackoff(attempt))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// sendResult sends the task result, transformed by ResultTransform if set, to the results channel,
+// or to the named output of the task, writing it encoded to EncodedOutput first, if set.
+// If the channel is full, the result is dropped once workers are told to drop pending results
+// or the task is abandoned.
+func (w *worker[R]) sendResult(t task[R], result R) {
+	if w.owner.transform != nil {
+		result = w.owner.transform(result)
+	}
+
+	if w.owner.encode != nil {
+		if err := w.owner.writeEncoded(result); err != nil {
+			_ = w.sendError(err)
+		}
+
+		if w.owner.config.SinksOnly {
+			return
+		}
+	}
+
+	if w.owner.abandoned != nil {
+		w.owner.sendMu.RLock()
+		defer w.owner.sendMu.RUnlock()
+
+		if w.owner.isAbandoned() {
+			w.owner.droppedResults.Add(1)
+			return
+		}
+	}
+
+	results := w.results
+	if o, ok := unwrapTo[*taskOutput[R]](t); ok {
+		results = w.owner.outputs[o.name]
+	}
+
+	select {
+	case results <- result:
+		w.sent(results)
 		return
+	default:
+	}
+
+	select {
+	case results <- result:
+		w.sent(results)
+	case <-w.owner.dropResults:
+		w.owner.droppedResults.Add(1)
+	case <-w.owner.abandoned:
+		w.owner.droppedResults.Add(1)
+	}
+}
+
+// sent counts the result sent to the results channel, unless it has been sent to another one.
+func (w *worker[R]) sent(results chan R) {
+	if results == w.owner.results {
+		w.owner.sentResults.Add(1)
+	}
+}
+
+// fail reports the task error, counting the task as failed unless the error is dropped by ErrorFilter.
+// It returns the reported error, or nil if it is dropped.
+func (w *worker[R]) fail(err error) error {
+	if err = w.sendError(err); err != nil {
+		w.owner.failed.Add(1)
 	}
 
-	if _, ok := t.(*taskError[R]); !ok {
-		w.results <- result
+	return err
+}
+
+// sendError sends the error, transformed by ErrorFilter if set and prefixed with Name, to the errors channel.
+// It returns the sent error, or nil if it is dropped by ErrorFilter.
+func (w *worker[R]) sendError(err error) error {
+	if w.owner.config.ErrorFilter != nil {
+		if err = w.owner.config.ErrorFilter(err); err == nil {
+			return nil
+		}
 	}
+
+	if w.owner.config.Name != "" {
+		err = fmt.Errorf("[%s] %w", w.owner.config.Name, err)
+	}
+
+	if w.owner.recentErrors != nil {
+		w.owner.recentErrors.add(err)
+	}
+
+	if w.owner.abandoned != nil {
+		w.owner.sendMu.RLock()
+		defer w.owner.sendMu.RUnlock()
+
+		if w.owner.isAbandoned() {
+			w.owner.droppedErrors.Add(1)
+			return err
+		}
+	}
+
+	select {
+	case w.errors <- err:
+	case <-w.owner.abandoned:
+		w.owner.droppedErrors.Add(1)
+	}
+
+	return err
+}
+
+// deadlineError wraps a task error with ErrTaskCancelled if the task context deadline has been exceeded.
+func deadlineError(ctx context.Context, err error) error {
+	if err == nil || errors.Is(err, ErrTaskCancelled) || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", ErrTaskCancelled, err)
 }
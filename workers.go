@@ -2,163 +2,1435 @@ package workers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/ygrebnov/workers/pool"
 )
 
 type Config struct {
+	// Name, if set, identifies workers in the errors sent to the errors channel, prefixed with "[Name] ".
+	Name string
+
 	MaxWorkers uint
 
+	// DynamicPoolMax, if set, limits the number of tasks executed at once with a dynamic pool,
+	// i.e. with MaxWorkers not set. Tasks wait in the queue while the limit is reached.
+	DynamicPoolMax uint
+
 	// StartImmediately defines whether workers start executing tasks immediately or not.
 	StartImmediately bool
 
 	// StopOnError stops tasks execution if an error occurs.
 	StopOnError bool
 
+	// TasksBufferSize defines the capacity of the tasks queue.
+	// AddTask blocks while the queue is full.
 	TasksBufferSize uint
+
+	// Queue, if set, replaces the internal tasks queue. TasksBufferSize is ignored in this case.
+	Queue Queue
+
+	// MaxQueueDepth, if set, limits the number of tasks added but not dispatched yet.
+	// AddTask returns ErrQueueFull instead of blocking once the limit is reached.
+	// Without Queue or Priority, it must not exceed TasksBufferSize, so that the queue has room for the tasks.
+	MaxQueueDepth uint
+
+	// Priority makes tasks dispatched in the order of priorities passed to AddTaskPriority,
+	// lower values first. Tasks of the same priority, including the ones passed to AddTask
+	// with priority zero, are dispatched in the order of adding. Priority cannot be used with Queue.
+	Priority bool
+
+	// RateLimit, if set, limits the number of tasks dispatched per second.
+	// RateBurst is the number of tasks which can be dispatched at once, one if not set.
+	RateLimit float64
+	RateBurst uint
+
+	// FairAdmission makes AddTask calls blocked on a full tasks queue
+	// enqueue their tasks in the order of the calls.
+	FairAdmission bool
+
+	// Prewarm defines the number of workers created in Start before any task is executed.
+	// For a fixed pool, the value is limited by MaxWorkers.
+	Prewarm uint
+
+	// HeartbeatInterval defines how often OnHeartbeat is called after Start. Zero disables heartbeats.
+	// HeartbeatTicks, if set, replaces the interval: OnHeartbeat is called on receiving each tick from it.
+	HeartbeatInterval time.Duration
+	HeartbeatTicks    <-chan time.Time
+
+	// OnHeartbeat receives the current stats every HeartbeatInterval, even if no tasks are executed.
+	OnHeartbeat func(Stats)
+
+	// RecentErrors is the number of the most recent task errors kept to be returned by RecentErrors.
+	RecentErrors uint
+
+	// PanicHandler, if set, is called with the recovered value when a task panics,
+	// in the panicking goroutine, before the panic is reported. Its own panics are ignored.
+	PanicHandler func(recovered interface{})
+
+	// EmitResultOnError makes tasks returning both a result and an error emit the result
+	// along with the error.
+	EmitResultOnError bool
+
+	// ResultsBeforeErrors makes Close wait until all results are received before closing the errors channel.
+	// Results are then handed to the receiver one by one through an unbuffered results channel.
+	// Close blocks while results are not received, until they are dropped with CloseDropUnreadGrace
+	// or ForceCancelGrace.
+	ResultsBeforeErrors bool
+
+	// ReliableResults makes New fail if an option dropping results, ResultTTL, CloseDropUnreadGrace
+	// or ForceCancelGrace, is set, guaranteeing that every result is sent to the results channel,
+	// which must be read for workers to progress.
+	ReliableResults bool
+
+	// ResultTTL defines how long a result may wait to be received before it is dropped.
+	// Zero means results are never dropped.
+	ResultTTL time.Duration
+
+	// SerializedResults makes results sent by workers pass through a single goroutine
+	// before reaching the results channel.
+	SerializedResults bool
+
+	// CloseDropUnreadGrace, if set, makes Close drop the results which cannot be sent because nobody receives them,
+	// once the results channel has been full with none of its results received for the grace period.
+	// Close still waits for dispatched tasks to complete, and drops their results after that.
+	CloseDropUnreadGrace time.Duration
+
+	// ForceCancelGrace, if set, limits the time Close waits for dispatched tasks to complete
+	// once the context passed to New is cancelled. After that, Close proceeds without waiting for the tasks
+	// ignoring the cancellation, and their results and errors are dropped.
+	ForceCancelGrace time.Duration
+
+	// ShutdownSignals make started workers close on receiving any of the signals.
+	// ShutdownChannel, if set, is received from instead of being notified of ShutdownSignals.
+	// ShutdownGrace, if set, limits the time closing waits for the executed tasks, as CloseContext does.
+	ShutdownSignals []os.Signal
+	ShutdownChannel <-chan os.Signal
+	ShutdownGrace   time.Duration
+
+	// ResultKey, if set, must be a func(R) interface{} returning a comparable key of a result.
+	// Only the first result with a given key is sent to the results channel, the following ones are dropped.
+	// A result with a key which is not comparable is sent unchanged and counted in Stats.InvalidResultKeys.
+	ResultKey interface{}
+
+	// Lifetime, if set, limits the time workers run after Start. Once it elapses,
+	// tasks dispatching stops and the context of executed tasks is cancelled.
+	Lifetime time.Duration
+
+	// TaskTimeout, if set, limits the execution time of each task. The error of a task
+	// which has not completed in time wraps ErrTaskCancelled.
+	TaskTimeout time.Duration
+
+	// RetryAttempts defines how many times a task returning an error is executed again.
+	// Tasks are not retried after a panic, the workers context cancellation, or an error wrapping ErrTaskCancelled.
+	RetryAttempts uint
+
+	// RetryBackoff, if set, returns the time to wait before the given retry attempt, starting from 1.
+	RetryBackoff func(attempt uint) time.Duration
+
+	// ContextValues, if set, is called with the context of each task execution to return the context
+	// passed to the task, e.g. with request-scoped values added. The returned context must be derived
+	// from the passed one to keep the task cancellation.
+	ContextValues func(context.Context) context.Context
+
+	// Middleware is a list of func(next func(context.Context) (R, error)) func(context.Context) (R, error)
+	// wrapping each added task. The first middleware is the outermost one.
+	Middleware []interface{}
+
+	// ResultsDone and ErrorsDone, if set, are closed by the caller after consuming all results and errors.
+	// Close waits for them after closing the results and errors channels.
+	ResultsDone <-chan struct{}
+	ErrorsDone  <-chan struct{}
+
+	// WorkerInit, if set, is called before a worker executes its first task to create the worker state,
+	// such as a connection, available to the tasks executed by the worker via WorkerState.
+	// If it fails, the error is reported instead of executing the task, and it is called again for the next one.
+	// WorkerShutdown, if set, is called on Close with the state of each initialized worker, after all
+	// dispatched tasks have completed. WorkerInit requires a fixed pool, i.e. MaxWorkers to be set,
+	// as a dynamic pool drops and creates workers at any time. With WorkerInit, at most MaxWorkers tasks
+	// are executed at once, so that each worker state is used by a single task at a time.
+	WorkerInit     func() (interface{}, error)
+	WorkerShutdown func(interface{})
+
+	// NamedOutputs are the names of additional results channels, returned by Output, receiving
+	// the results of tasks added with TaskToOutput. The results are sent to them directly, bypassing
+	// ResultTTL, SerializedResults, ResultKey and ResultSink. Close closes them along with the results channel.
+	NamedOutputs []string
+
+	// ResultSink, if set, is a func(R) called for each result on a single goroutine, before the result
+	// is sent to the results channel. ErrorSink, if set, is called for each error, similarly.
+	// A slow sink makes tasks block on sending their results or errors.
+	// Close waits for the sinks to handle all results and errors.
+	ResultSink interface{}
+	ErrorSink  func(error)
+
+	// ErrorFilter, if set, is called by workers with each error before sending it to the errors channel,
+	// and the returned error is sent instead. If it returns nil, the error is dropped: it is not sent,
+	// the task is not counted as failed, and it does not stop workers with StopOnError.
+	ErrorFilter func(error) error
+
+	// ErrorCoalesceWindow, if set, delays each error by the window, during which identical errors,
+	// by their messages, are counted instead of being sent. The error is then sent wrapped with
+	// the count, which is returned by ExtractRepeatCount.
+	ErrorCoalesceWindow time.Duration
+
+	// ResultEncoder, if set, is a func(R) ([]byte, error) encoding each result on the worker
+	// which has produced it. Encoded results are written to EncodedOutput, each prefixed with
+	// its length as a big-endian uint32. Encoding and writing errors are sent to the errors channel.
+	ResultEncoder interface{}
+	EncodedOutput io.Writer
+
+	// ResultTransform, if set, is a func(R) R called by workers with each result before sending it,
+	// and the returned result is sent instead. It is not called for tasks returning only an error.
+	ResultTransform interface{}
+
+	// EndMarker, if set, is a func() R producing a value which Close sends to the results channel
+	// as the last one before closing it. Close blocks while the value cannot be sent.
+	EndMarker interface{}
+
+	// SinksOnly makes results and errors passed to the sinks, and encoded results,
+	// not sent to the results and errors channels.
+	SinksOnly bool
+
+	// RequireCancellableContext makes New fail if the passed context can never be cancelled.
+	RequireCancellableContext bool
+}
+
+// Validate returns an error wrapping ErrInvalidConfig if options of the config conflict with each other.
+// The types of the options depending on the results type are checked by New.
+func (c *Config) Validate() error {
+	if c.DynamicPoolMax > 0 && c.MaxWorkers > 0 {
+		return fmt.Errorf("%w: DynamicPoolMax is set with a fixed pool", ErrInvalidConfig)
+	}
+
+	if c.ReliableResults && (c.ResultTTL > 0 || c.CloseDropUnreadGrace > 0 || c.ForceCancelGrace > 0) {
+		return fmt.Errorf("%w: ReliableResults is set with an option dropping results", ErrInvalidConfig)
+	}
+
+	if c.MaxQueueDepth > c.TasksBufferSize && c.Queue == nil && !c.Priority {
+		return fmt.Errorf("%w: MaxQueueDepth exceeds TasksBufferSize", ErrInvalidConfig)
+	}
+
+	if c.WorkerInit != nil && c.MaxWorkers == 0 {
+		return fmt.Errorf("%w: WorkerInit is set with a dynamic pool", ErrInvalidConfig)
+	}
+
+	if c.HeartbeatInterval < 0 {
+		return fmt.Errorf("%w: negative heartbeat interval", ErrInvalidConfig)
+	}
+
+	if (c.HeartbeatInterval > 0 || c.HeartbeatTicks != nil) && c.OnHeartbeat == nil {
+		return fmt.Errorf("%w: heartbeat interval is set without a callback", ErrInvalidConfig)
+	}
+
+	if c.ResultEncoder != nil && c.EncodedOutput == nil {
+		return fmt.Errorf("%w: ResultEncoder is set without EncodedOutput", ErrInvalidConfig)
+	}
+
+	if c.EndMarker != nil && c.SinksOnly {
+		return fmt.Errorf("%w: EndMarker is set with SinksOnly", ErrInvalidConfig)
+	}
+
+	if c.Priority && c.Queue != nil {
+		return fmt.Errorf("%w: Priority cannot be used with a custom queue", ErrInvalidConfig)
+	}
+
+	return nil
 }
 
 type Workers[R interface{}] interface {
 	Start(context.Context)
+	StartErr(context.Context) error
 	AddTask(interface{}) error
+	AddTaskCancelable(interface{}) (context.CancelFunc, error)
+	TryAddTaskContext(context.Context, interface{}) (bool, error)
+	AddTasks([]interface{}) (int, error)
+	AddTaskPriority(interface{}, int) error
 	GetResults() chan R
+	Results() func(yield func(R, error) bool)
+	Output(string) chan R
 	GetErrors() chan error
+	Close()
+	CloseErr() error
+	CloseContext(context.Context) error
+	DroppedResults() uint64
+	QueueStats() (depth int, enqueued uint64, dequeued uint64)
+	Stats() Stats
+	Settings() Settings
+	BusyWorkers() int
+	IdleWorkers() int
+	ErrorsFuture() *Future[error]
+	CloseCollect() error
+	ConsumeErrors(func(error)) <-chan struct{}
+	RecentErrors() []error
+	Undispatched() int
+	Drain(context.Context) error
 }
 
 type workers[R interface{}] struct {
 	config *Config
 
+	// parentCtx is the context passed to New.
+	parentCtx context.Context
+
+	middleware []middleware[R]
+
+	mu        sync.Mutex
 	isStarted bool
+	isClosed  bool
+	cancel    context.CancelFunc
+
+	closing   chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+
+	// recentErrors, if not nil, keeps the most recent task errors.
+	recentErrors *errorRing
+
+	errorsFuture     *Future[error]
+	errorsFutureOnce sync.Once
+	closeCollectOnce sync.Once
+
+	// onStopped, if not nil, is called on closing after all dispatched tasks have completed.
+	onStopped func()
 
 	pool pool.Pool
 
-	tasks   chan task[R]
-	results chan R
-	errors  chan error
+	// active, if not nil, limits the number of tasks executed at once with a dynamic pool or WorkerInit.
+	active chan struct{}
+
+	// states are the states of the workers created with WorkerInit.
+	statesMu sync.Mutex
+	states   []interface{}
+
+	admission fifoMutex
+
+	// queueSlots, if not nil, limits the number of tasks added but not dispatched yet.
+	queueSlots chan struct{}
+
+	// queue, if not nil, is the tasks queue from which tasks are pumped to the tasks channel.
+	queue Queue
+
+	// pull, if not nil, requests pump to dequeue a task for the dispatcher.
+	pull chan struct{}
+
+	tasks      chan task[R]
+	results    chan R
+	outputs    map[string]chan R
+	resultSink func(R)
+	transform  func(R) R
+	endMarker  func() R
+	errors     chan error
+
+	// encode, if not nil, encodes results written to EncodedOutput under encodedMu.
+	encode    func(R) ([]byte, error)
+	encodedMu sync.Mutex
+
+	// errorsIn receives published errors. If ErrorSink or ErrorCoalesceWindow is set, they are
+	// forwarded to errors by publishErrors. Otherwise, errorsIn is errors.
+	errorsIn      chan error
+	publisherDone chan struct{}
+
+	// dropResults, if not nil, is closed to make pending results sends give up.
+	dropResults chan struct{}
+
+	// abandoned, if not nil, is closed once Close stops waiting for the dispatched tasks.
+	// Results and errors are sent under the read lock of sendMu to be dropped after that.
+	abandoned chan struct{}
+	sendMu    sync.RWMutex
+
+	// resultsIn, if not nil, receives results from workers to be forwarded to results by collect.
+	resultsIn     chan R
+	resultKey     func(R) interface{}
+	collectorDone chan struct{}
+
+	enqueued       atomic.Uint64
+	dequeued       atomic.Uint64
+	inFlight       atomic.Int64
+	completed      atomic.Uint64
+	failed         atomic.Uint64
+	retries        atomic.Uint64
+	droppedResults atomic.Uint64
+	sentResults    atomic.Uint64
+	droppedErrors  atomic.Uint64
+
+	// invalidResultKeys is the number of results for which ResultKey has returned a key which is not comparable.
+	invalidResultKeys atomic.Uint64
+
+	// undispatched is the number of tasks remaining in the queue on closing.
+	undispatched atomic.Int64
+
+	// progress, if not nil, is closed once a task completes, to wake up Drain callers.
+	progressMu sync.Mutex
+	progress   chan struct{}
 }
 
 type workersStoppable[R interface{}] struct {
 	*workers[R]
 
-	errorsBuf chan error
+	errorsBuf     chan error
+	forwarderDone chan struct{}
 }
 
-func New[R interface{}](ctx context.Context, config *Config) Workers[R] {
-	r := make(chan R, 1024)
+// New creates workers with the given config, or the default one if config is nil.
+// It returns an error wrapping ErrInvalidConfig if config is invalid, or ErrInvalidState if ctx is already done.
+// Previous versions of New returned only Workers, callers must now handle the returned error.
+func New[R interface{}](ctx context.Context, config *Config) (Workers[R], error) {
+	if config == nil {
+		config = &Config{}
+	}
 
-	eCapacity := 1024
-	if config != nil && config.StopOnError {
-		eCapacity = 100
+	// Tasks cannot be added to workers created with a cancelled context.
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidState, err)
 	}
-	e := make(chan error, eCapacity)
 
-	newWorkerFn := func() interface{} {
-		return newWorker(r, e)
+	if config.RequireCancellableContext && ctx.Done() == nil {
+		return nil, fmt.Errorf("%w: context can never be cancelled", ErrInvalidConfig)
 	}
 
-	var p pool.Pool
-	if config != nil && config.MaxWorkers > 0 {
-		p = pool.NewFixed(config.MaxWorkers, newWorkerFn)
-	} else {
-		p = pool.NewDynamic(newWorkerFn)
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
 
-	var w Workers[R]
-	if config != nil && config.StopOnError {
-		w = &workersStoppable[R]{
-			workers: &workers[R]{
-				config:  config,
-				tasks:   make(chan task[R]),
-				results: r,
-				errors:  make(chan error, 1024),
-				pool:    p,
-			},
-			errorsBuf: e,
-		}
-	} else {
-		w = &workers[R]{
-			config:  config,
-			tasks:   make(chan task[R]),
-			results: r,
-			errors:  e,
-			pool:    p,
-		}
+	w, err := newWorkers[R](ctx, config)
+	if err != nil {
+		return nil, err
 	}
 
-	if config != nil && config.StartImmediately {
+	if config.StartImmediately {
 		w.Start(ctx)
 	}
 
-	return w
+	return w, nil
 }
 
-func (w *workers[R]) Start(ctx context.Context) {
-	if w.isStarted {
-		return
+// newWorkers creates workers with the channels, pool and forwarders required by config.
+func newWorkers[R interface{}](ctx context.Context, config *Config) (Workers[R], error) {
+	w := &workers[R]{
+		config:    config,
+		parentCtx: ctx,
+		closing:   make(chan struct{}),
+		done:      make(chan struct{}),
 	}
-	w.isStarted = true
 
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
+	if err := w.setCallbacks(); err != nil {
+		return nil, err
+	}
 
-			case t := <-w.tasks:
-				go w.dispatch(ctx, t)
-			}
+	if err := w.makeOutputs(); err != nil {
+		return nil, err
+	}
+
+	wr, we := w.makeResults(), w.makeErrors()
+	w.makeQueue()
+	w.makePool(wr, we)
+
+	if config.CloseDropUnreadGrace > 0 {
+		w.dropResults = make(chan struct{})
+	}
+
+	if config.ForceCancelGrace > 0 {
+		w.abandoned = make(chan struct{})
+	}
+
+	if config.RecentErrors > 0 {
+		w.recentErrors = newErrorRing(config.RecentErrors)
+	}
+
+	if wr != w.results {
+		w.resultsIn = wr
+		w.collectorDone = make(chan struct{})
+		go w.collect()
+	}
+
+	if w.errorsIn != w.errors {
+		w.publisherDone = make(chan struct{})
+		go w.publishErrors()
+	}
+
+	if config.StopOnError {
+		ws := &workersStoppable[R]{
+			workers:       w,
+			errorsBuf:     we,
+			forwarderDone: make(chan struct{}),
 		}
-	}()
+		w.onStopped = ws.stopForwarding
+		return ws, nil
+	}
+
+	return w, nil
+}
+
+// typedOption returns the config option v, which is set as interface{}, as T.
+// It returns an error wrapping ErrInvalidConfig if v is set to a value of another type.
+func typedOption[T interface{}](v interface{}, name string) (T, error) {
+	var typed T
+	if v == nil {
+		return typed, nil
+	}
+
+	typed, ok := v.(T)
+	if !ok {
+		return typed, fmt.Errorf("%w: %s must be %T", ErrInvalidConfig, name, typed)
+	}
+
+	return typed, nil
+}
+
+// setCallbacks sets the callbacks which depend on the results type from the config options.
+func (w *workers[R]) setCallbacks() error {
+	var err error
+
+	if w.resultSink, err = typedOption[func(R)](w.config.ResultSink, "ResultSink"); err != nil {
+		return err
+	}
+
+	if w.encode, err = typedOption[func(R) ([]byte, error)](w.config.ResultEncoder, "ResultEncoder"); err != nil {
+		return err
+	}
+
+	if w.transform, err = typedOption[func(R) R](w.config.ResultTransform, "ResultTransform"); err != nil {
+		return err
+	}
+
+	if w.endMarker, err = typedOption[func() R](w.config.EndMarker, "EndMarker"); err != nil {
+		return err
+	}
+
+	if w.resultKey, err = typedOption[func(R) interface{}](w.config.ResultKey, "ResultKey"); err != nil {
+		return err
+	}
+
+	w.middleware = make([]middleware[R], len(w.config.Middleware))
+	for i, m := range w.config.Middleware {
+		typed, ok := m.(func(func(context.Context) (R, error)) func(context.Context) (R, error))
+		if !ok {
+			return fmt.Errorf("%w: invalid middleware type %T", ErrInvalidConfig, m)
+		}
+		w.middleware[i] = typed
+	}
+
+	return nil
+}
+
+// makeOutputs creates the named outputs channels.
+func (w *workers[R]) makeOutputs() error {
+	if len(w.config.NamedOutputs) == 0 {
+		return nil
+	}
+
+	w.outputs = make(map[string]chan R, len(w.config.NamedOutputs))
+	for _, name := range w.config.NamedOutputs {
+		if _, ok := w.outputs[name]; ok {
+			return fmt.Errorf("%w: duplicate output %q", ErrInvalidConfig, name)
+		}
+		w.outputs[name] = make(chan R, resultsBufferSize)
+	}
+
+	return nil
+}
+
+// makeResults creates the results channel, and returns the channel workers send results to,
+// which differs from it if results are forwarded by collect.
+func (w *workers[R]) makeResults() chan R {
+	config := w.config
+
+	var wr chan R
+	switch {
+	case config.ResultTTL > 0:
+		w.results, wr = make(chan R), make(chan R)
+	case w.resultSink != nil && config.SinksOnly:
+		w.results, wr = make(chan R), make(chan R)
+	case config.SerializedResults || w.resultKey != nil || w.resultSink != nil:
+		w.results, wr = make(chan R, resultsBufferSize), make(chan R)
+	default:
+		w.results = make(chan R, resultsBufferSize)
+		wr = w.results
+	}
+
+	// With ResultsBeforeErrors, collect completes once all results have been received.
+	if config.ResultsBeforeErrors {
+		if config.ResultTTL == 0 {
+			wr = make(chan R, resultsBufferSize)
+		}
+		w.results = make(chan R)
+	}
+
+	return wr
+}
+
+// makeErrors creates the errors channel and the channel errors are published to, which differs from it
+// if errors are forwarded by publishErrors. It returns the channel workers send errors to, which differs
+// from the latter if errors are forwarded by forwardErrors.
+func (w *workers[R]) makeErrors() chan error {
+	config := w.config
+
+	switch {
+	case config.ErrorSink != nil && config.SinksOnly:
+		w.errors, w.errorsIn = make(chan error), make(chan error, errorsBufferSize)
+	case config.ErrorSink != nil || config.ErrorCoalesceWindow > 0:
+		w.errors, w.errorsIn = make(chan error, errorsBufferSize), make(chan error, errorsBufferSize)
+	default:
+		w.errors = make(chan error, errorsBufferSize)
+		w.errorsIn = w.errors
+	}
+
+	if config.StopOnError {
+		return make(chan error, 100)
+	}
+
+	return w.errorsIn
+}
+
+// makeQueue creates the tasks channel and the queue tasks are pumped from, if any.
+func (w *workers[R]) makeQueue() {
+	w.queue = w.config.Queue
+	if w.config.Priority {
+		w.queue = newPriorityQueue[R]()
+	}
+
+	tasksBufferSize := w.config.TasksBufferSize
+	if w.queue != nil {
+		tasksBufferSize = 0
+		w.pull = make(chan struct{})
+	}
+	w.tasks = make(chan task[R], tasksBufferSize)
+
+	if w.config.MaxQueueDepth > 0 {
+		w.queueSlots = make(chan struct{}, w.config.MaxQueueDepth)
+	}
+}
+
+// makePool creates the pool of workers sending results to wr and errors to we,
+// and the limit of tasks executed at once, if any.
+func (w *workers[R]) makePool(wr chan R, we chan error) {
+	newWorkerFn := func() interface{} {
+		return newWorker(wr, we, w)
+	}
+
+	switch {
+	case w.config.WorkerInit != nil:
+		// Each worker state must be used by a single task at a time, so the number of tasks
+		// executed at once is limited to the number of workers.
+		w.pool = pool.NewExclusive(w.config.MaxWorkers, newWorkerFn)
+		w.active = make(chan struct{}, w.config.MaxWorkers)
+	case w.config.MaxWorkers > 0:
+		w.pool = pool.NewFixed(w.config.MaxWorkers, newWorkerFn)
+	default:
+		w.pool = pool.NewDynamic(newWorkerFn)
+	}
+
+	if w.config.DynamicPoolMax > 0 {
+		w.active = make(chan struct{}, w.config.DynamicPoolMax)
+	}
+}
+
+// NewWithCancel is New which also returns a function stopping tasks dispatching and cancelling
+// the context of executed tasks. Unlike Close, the function does not close the results and errors channels.
+func NewWithCancel[R interface{}](ctx context.Context, config *Config) (Workers[R], context.CancelFunc, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	w, err := New[R](ctx, config)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	return w, func() {
+		cancel()
+		w.(interface{ stop() }).stop()
+	}, nil
+}
+
+func (w *workers[R]) Start(ctx context.Context) {
+	_ = w.StartErr(ctx)
+}
+
+// StartErr is Start which returns ErrAlreadyStarted if workers have already been started,
+// or ErrInvalidState if they are closed.
+func (w *workers[R]) StartErr(ctx context.Context) error {
+	ctx, err := w.start(ctx)
+	if err != nil {
+		return err
+	}
+
+	go w.run(ctx)
+	return nil
 }
 
 func (w *workersStoppable[R]) Start(ctx context.Context) {
+	_ = w.StartErr(ctx)
+}
+
+func (w *workersStoppable[R]) StartErr(ctx context.Context) error {
+	ctx, err := w.start(ctx)
+	if err != nil {
+		return err
+	}
+
+	go w.forwardErrors()
+	go w.run(ctx)
+	return nil
+}
+
+// start marks workers as started and creates the internal context.
+// It returns an error if workers have already been started or closed.
+func (w *workers[R]) start(ctx context.Context) (context.Context, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.isClosed {
+		return nil, ErrInvalidState
+	}
+
 	if w.isStarted {
-		return
+		return nil, ErrAlreadyStarted
 	}
 	w.isStarted = true
 
-	ctx, cancel := context.WithCancel(ctx)
+	if w.config.Lifetime > 0 {
+		ctx, w.cancel = context.WithTimeout(ctx, w.config.Lifetime)
+	} else {
+		ctx, w.cancel = context.WithCancel(ctx)
+	}
 
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
+	w.prewarm()
+	w.heartbeat(ctx)
+
+	if w.queue != nil {
+		go w.pump(ctx)
+	}
+
+	if len(w.config.ShutdownSignals) > 0 || w.config.ShutdownChannel != nil {
+		w.closeOnSignal()
+	}
+
+	return ctx, nil
+}
+
+// stop cancels the internal context of started workers.
+func (w *workers[R]) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cancel != nil {
+		w.cancel()
+	}
+}
+
+// stopped returns a channel which is closed once workers stop dispatching tasks.
+func (w *workers[R]) stopped() <-chan struct{} {
+	return w.done
+}
 
-			case t := <-w.tasks:
-				go w.dispatch(ctx, t)
+// run dispatches tasks until the context is cancelled or workers are closed.
+func (w *workers[R]) run(ctx context.Context) {
+	defer close(w.done)
 
-			case e := <-w.errorsBuf:
-				w.errors <- e
+	var limiter *rateLimiter
+	if w.config.RateLimit > 0 {
+		limiter = newRateLimiter(w.config.RateLimit, w.config.RateBurst)
+	}
+
+	for {
+		if limiter != nil && !w.waitToken(ctx, limiter) {
+			return
+		}
+
+		if !w.acquireWorker(ctx) {
+			return
+		}
+
+		if !w.pullTask(ctx) {
+			w.releaseWorker()
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			w.releaseWorker()
+			return
 
-				if w.config.StopOnError {
-					cancel()
-				}
+		case <-w.closing:
+			w.releaseWorker()
+			return
+
+		case t := <-w.tasks:
+			if w.queueSlots != nil {
+				<-w.queueSlots
+			}
+
+			if limiter != nil {
+				limiter.take()
 			}
+
+			w.dequeued.Add(1)
+			w.wg.Add(1)
+
+			if delay := time.Until(startAt(t)); delay > 0 {
+				w.releaseWorker()
+				go w.dispatchAfter(ctx, t, delay)
+				continue
+			}
+
+			w.inFlight.Add(1)
+			go w.dispatch(ctx, t)
 		}
-	}()
+	}
+}
+
+// pullTask requests a task from pump, if a queue is set.
+// It returns false if the context is cancelled or workers are closed first.
+func (w *workers[R]) pullTask(ctx context.Context) bool {
+	if w.pull == nil {
+		return true
+	}
+
+	select {
+	case w.pull <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-w.closing:
+		return false
+	}
+}
+
+// stopForwarding waits for forwardErrors to handle all buffered errors.
+func (w *workersStoppable[R]) stopForwarding() {
+	close(w.errorsBuf)
+	<-w.forwarderDone
+}
+
+// forwardErrors forwards the first error to the errors channel and stops tasks execution.
+// Errors occurring after that are discarded.
+func (w *workersStoppable[R]) forwardErrors() {
+	defer close(w.forwarderDone)
+
+	var stopped bool
+	for e := range w.errorsBuf {
+		if stopped {
+			w.droppedErrors.Add(1)
+			continue
+		}
+
+		w.errorsIn <- e
+		w.cancel()
+		stopped = true
+	}
 }
 
+// AddTask adds a task to the queue, blocking while the queue is full.
+// If MaxQueueDepth is reached, it returns ErrQueueFull without blocking.
+// It returns an error if workers are closed or stopped, or if the context passed to New is cancelled.
 func (w *workers[R]) AddTask(t interface{}) error {
 	tt, err := newTask[R](t)
 	if err != nil {
 		return err
 	}
 
-	w.tasks <- tt
+	return w.add(context.Background(), tt)
+}
+
+// TryAddTaskContext is AddTask which waits while the queue is full until ctx is done.
+// It returns false and no error if the task has not been added before ctx is done,
+// and an error, such as ErrQueueFull once MaxQueueDepth is reached, if the task cannot be added at all.
+// A task is added if there is room in the queue even though ctx is already done.
+func (w *workers[R]) TryAddTaskContext(ctx context.Context, t interface{}) (bool, error) {
+	tt, err := newTask[R](t)
+	if err != nil {
+		return false, err
+	}
+
+	if err = w.add(ctx, tt); err != nil {
+		if ctx.Err() != nil && !errors.Is(err, ErrInvalidState) && !errors.Is(err, ErrQueueFull) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// AddTasks adds tasks in order, as AddTask does, stopping at the first task which cannot be added.
+// It returns the number of added tasks and the error of the first task not added.
+func (w *workers[R]) AddTasks(tasks []interface{}) (int, error) {
+	for i, t := range tasks {
+		if err := w.AddTask(t); err != nil {
+			return i, err
+		}
+	}
+
+	return len(tasks), nil
+}
+
+// AddTaskPriority is AddTask for workers with Priority set, adding a task with the given priority.
+// Lower values are dispatched first. It returns ErrInvalidConfig if Priority is not set.
+func (w *workers[R]) AddTaskPriority(t interface{}, priority int) error {
+	if !w.config.Priority {
+		return fmt.Errorf("%w: Priority is not set", ErrInvalidConfig)
+	}
+
+	tt, err := newTask[R](t)
+	if err != nil {
+		return err
+	}
+
+	return w.add(context.Background(), &taskPriority[R]{task: tt, priority: priority})
+}
+
+// AddTaskCancelable is AddTask which also returns a function cancelling the context of the added task only.
+// A task cancelled before being executed is not executed. The error of a cancelled task wraps ErrTaskCancelled.
+func (w *workers[R]) AddTaskCancelable(t interface{}) (context.CancelFunc, error) {
+	tt, err := newTask[R](t)
+	if err != nil {
+		return nil, err
+	}
+
+	token, cancel := context.WithCancel(context.Background())
+	if err = w.add(context.Background(), &taskCancelable[R]{task: tt, token: token}); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return cancel, nil
+}
+
+// add adds the created task to the queue, waiting while the queue is full until ctx is done.
+func (w *workers[R]) add(ctx context.Context, tt task[R]) error {
+	if o, ok := unwrapTo[*taskOutput[R]](tt); ok {
+		if _, ok = w.outputs[o.name]; !ok {
+			return fmt.Errorf("%w: unknown output %q", ErrInvalidConfig, o.name)
+		}
+	}
+
+	tt = w.withMiddleware(tt)
+
+	if w.config.FairAdmission {
+		if err := w.admission.LockContext(ctx); err != nil {
+			return err
+		}
+		defer w.admission.Unlock()
+	}
+
+	select {
+	case <-w.closing:
+		return ErrInvalidState
+	default:
+	}
+
+	if w.queueSlots == nil {
+		return w.enqueue(ctx, tt)
+	}
+
+	select {
+	case w.queueSlots <- struct{}{}:
+	default:
+		return ErrQueueFull
+	}
+
+	if err := w.enqueue(ctx, tt); err != nil {
+		<-w.queueSlots
+		return err
+	}
+
 	return nil
 }
 
+// enqueue sends the task to the queue, blocking while the queue is full until ctx is done.
+func (w *workers[R]) enqueue(ctx context.Context, tt task[R]) error {
+	if w.queue != nil {
+		queueCtx := w.parentCtx
+		if ctx.Done() != nil {
+			var cancel context.CancelFunc
+			queueCtx, cancel = context.WithCancel(queueCtx)
+			defer cancel()
+			defer context.AfterFunc(ctx, cancel)()
+		}
+
+		if err := w.queue.Enqueue(queueCtx, tt); err != nil {
+			return err
+		}
+
+		w.enqueued.Add(1)
+		return nil
+	}
+
+	// The task is added if there is room in the queue, whether ctx is done or not.
+	select {
+	case w.tasks <- tt:
+		w.enqueued.Add(1)
+		return nil
+	default:
+	}
+
+	select {
+	case w.tasks <- tt:
+		w.enqueued.Add(1)
+		return nil
+
+	case <-w.closing:
+		return ErrInvalidState
+
+	case <-w.done:
+		return ErrInvalidState
+
+	case <-w.parentCtx.Done():
+		return fmt.Errorf("%w: %w", ErrInvalidState, w.parentCtx.Err())
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (w *workers[R]) GetResults() chan R {
 	return w.results
 }
 
+// Output returns the named output channel, or nil if name is not one of NamedOutputs.
+func (w *workers[R]) Output(name string) chan R {
+	return w.outputs[name]
+}
+
 func (w *workers[R]) GetErrors() chan error {
 	return w.errors
 }
 
+// ErrorsFuture returns a future resolved, after Close, to all errors joined.
+// The errors are received from the errors channel, which must not be read elsewhere.
+func (w *workers[R]) ErrorsFuture() *Future[error] {
+	w.errorsFutureOnce.Do(func() {
+		w.errorsFuture = newFuture[error]()
+
+		go func() {
+			var errs []error
+			for err := range w.errors {
+				errs = append(errs, err)
+			}
+
+			w.errorsFuture.resolve(errors.Join(errs...))
+		}()
+	})
+
+	return w.errorsFuture
+}
+
+// ConsumeErrors calls fn for each error received from the errors channel, which must not be read elsewhere,
+// on a separate goroutine. It returns a channel which is closed once the errors channel is closed and
+// all errors are handled.
+func (w *workers[R]) ConsumeErrors(fn func(error)) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for err := range w.errors {
+			fn(err)
+		}
+	}()
+
+	return done
+}
+
+// Drain waits until all added tasks have completed, without closing workers, so more tasks
+// can be added afterwards. It returns ctx.Err() if ctx is done first, or ErrInvalidState
+// if workers are stopped or closed with tasks remaining in the queue.
+// Drain is safe to call concurrently. Tasks added while Drain waits may or may not be waited for.
+func (w *workers[R]) Drain(ctx context.Context) error {
+	var (
+		done    = w.done
+		stopped bool
+	)
+
+	for {
+		// The channel is taken before the counters are read, so that no completion is missed.
+		progress := w.waitProgress()
+
+		if w.completed.Load() >= w.enqueued.Load() {
+			return nil
+		}
+
+		// Queued tasks are never dispatched after workers stop.
+		if stopped && w.inFlight.Load() == 0 {
+			return ErrInvalidState
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-progress:
+
+		case <-done:
+			stopped, done = true, nil
+		}
+	}
+}
+
+// waitProgress returns a channel closed once a task completes.
+func (w *workers[R]) waitProgress() <-chan struct{} {
+	w.progressMu.Lock()
+	defer w.progressMu.Unlock()
+
+	if w.progress == nil {
+		w.progress = make(chan struct{})
+	}
+
+	return w.progress
+}
+
+// signalProgress wakes up the callers waiting for a task to complete.
+func (w *workers[R]) signalProgress() {
+	w.progressMu.Lock()
+	defer w.progressMu.Unlock()
+
+	if w.progress != nil {
+		close(w.progress)
+		w.progress = nil
+	}
+}
+
+// withMiddleware wraps the task with the configured middleware.
+func (w *workers[R]) withMiddleware(t task[R]) task[R] {
+	if len(w.middleware) == 0 {
+		return t
+	}
+
+	fn := t.execute
+	for i := len(w.middleware) - 1; i >= 0; i-- {
+		fn = w.middleware[i](fn)
+	}
+
+	return &taskMiddleware[R]{task: t, fn: fn}
+}
+
+// Close stops dispatching tasks, waits for the dispatched ones to complete
+// and closes the results and errors channels, in this order. Tasks remaining in the queue are not executed.
+// Close blocks while dispatched tasks wait for their results or errors to be received.
+func (w *workers[R]) Close() {
+	w.closeOnce.Do(func() {
+		w.mu.Lock()
+		w.isClosed = true
+		isStarted := w.isStarted
+		close(w.closing)
+		w.mu.Unlock()
+
+		if w.dropResults != nil {
+			closed := make(chan struct{})
+			defer close(closed)
+
+			go w.dropUnread(closed)
+		}
+
+		if isStarted {
+			<-w.done
+			w.undispatched.Store(int64(w.enqueued.Load() - w.dequeued.Load()))
+			w.waitDispatched()
+			w.cancel()
+
+			if w.onStopped != nil {
+				w.onStopped()
+			}
+
+			w.shutdownWorkers()
+		} else {
+			close(w.done)
+			w.undispatched.Store(int64(w.enqueued.Load()))
+		}
+
+		w.closeResults()
+		w.closeErrors()
+
+		if w.config.ResultsDone != nil {
+			<-w.config.ResultsDone
+		}
+
+		if w.config.ErrorsDone != nil {
+			<-w.config.ErrorsDone
+		}
+	})
+}
+
+// CloseCollect closes workers, receiving errors concurrently so that unread errors do not block closing,
+// and returns all of them joined. The errors channel must not be read elsewhere.
+// Subsequent calls return nil.
+func (w *workers[R]) CloseCollect() error {
+	var err error
+	w.closeCollectOnce.Do(func() {
+		future := w.ErrorsFuture()
+		w.Close()
+		err = future.Get()
+	})
+
+	return err
+}
+
+// Undispatched returns the number of tasks which remained in the queue on closing and were never executed.
+// It returns zero until Close has been called.
+func (w *workers[R]) Undispatched() int {
+	return int(w.undispatched.Load())
+}
+
+// CloseErr is Close which returns an error wrapping ErrErrorsDropped if some task errors
+// have not been sent to the errors channel, e.g. the ones occurring after the first error with StopOnError.
+func (w *workers[R]) CloseErr() error {
+	w.Close()
+
+	if n := w.droppedErrors.Load(); n > 0 {
+		return fmt.Errorf("%w: %d", ErrErrorsDropped, n)
+	}
+
+	return nil
+}
+
+// CloseContext is Close which, if ctx is done before closing completes, cancels the context
+// of the executed tasks and returns ctx.Err() without waiting for them.
+// Closing then completes in the background once the tasks return.
+func (w *workers[R]) CloseContext(ctx context.Context) error {
+	closed := make(chan struct{})
+	go func() {
+		w.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+		return nil
+
+	case <-ctx.Done():
+		w.stop()
+		return ctx.Err()
+	}
+}
+
+// closeOnSignal makes workers close on receiving any of the shutdown signals.
+func (w *workers[R]) closeOnSignal() {
+	ch := w.config.ShutdownChannel
+
+	var notified chan os.Signal
+	if ch == nil {
+		notified = make(chan os.Signal, 1)
+		signal.Notify(notified, w.config.ShutdownSignals...)
+		ch = notified
+	}
+
+	go func() {
+		if notified != nil {
+			defer signal.Stop(notified)
+		}
+
+		select {
+		case <-ch:
+			w.closeWithGrace()
+
+		case <-w.closing:
+		}
+	}()
+}
+
+// closeWithGrace closes workers, with CloseContext limited by ShutdownGrace if set.
+func (w *workers[R]) closeWithGrace() {
+	if w.config.ShutdownGrace <= 0 {
+		w.Close()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.config.ShutdownGrace)
+	defer cancel()
+
+	_ = w.CloseContext(ctx)
+}
+
+// dropUnread makes pending results sends give up once the results channel has been full with none
+// of its results received for CloseDropUnreadGrace, until closed is closed.
+func (w *workers[R]) dropUnread(closed <-chan struct{}) {
+	ticker := time.NewTicker(w.config.CloseDropUnreadGrace)
+	defer ticker.Stop()
+
+	last := w.receivedResults()
+	for {
+		select {
+		case <-closed:
+			return
+
+		case <-ticker.C:
+			received := w.receivedResults()
+			if received == last && len(w.results) == cap(w.results) {
+				close(w.dropResults)
+				return
+			}
+
+			last = received
+		}
+	}
+}
+
+// receivedResults returns the number of results received from the results channel.
+func (w *workers[R]) receivedResults() int64 {
+	return int64(w.sentResults.Load()) - int64(len(w.results))
+}
+
+// waitDispatched waits for dispatched tasks to complete.
+// If ForceCancelGrace is set and the tasks have not completed in time after the context passed to New
+// is cancelled, they are abandoned.
+func (w *workers[R]) waitDispatched() {
+	if w.abandoned == nil {
+		w.wg.Wait()
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	var (
+		cancelled = w.parentCtx.Done()
+		force     <-chan time.Time
+	)
+
+	for {
+		select {
+		case <-done:
+			return
+
+		case <-cancelled:
+			force = time.After(w.config.ForceCancelGrace)
+			cancelled = nil
+
+		case <-force:
+			close(w.abandoned)
+
+			// Wait for the pending sends to give up.
+			w.sendMu.Lock()
+			w.sendMu.Unlock()
+			return
+		}
+	}
+}
+
+// isAbandoned returns true if Close has stopped waiting for the dispatched tasks.
+func (w *workers[R]) isAbandoned() bool {
+	select {
+	case <-w.abandoned:
+		return true
+	default:
+		return false
+	}
+}
+
+// dispatch executes the task with a worker from the pool. The worker must have been acquired with acquireWorker.
 func (w *workers[R]) dispatch(ctx context.Context, t task[R]) {
+	defer func() {
+		w.releaseWorker()
+		w.completed.Add(1)
+		w.inFlight.Add(-1)
+		w.signalProgress()
+		w.wg.Done()
+	}()
+
 	ww := w.pool.Get().(*worker[R])
 	ww.execute(ctx, t)
 	w.pool.Put(ww)
 }
+
+// acquireWorker waits for executing tasks to be fewer than DynamicPoolMax, or MaxWorkers with WorkerInit,
+// if set, and reserves a worker. It returns false if the context is cancelled or workers are closed first.
+func (w *workers[R]) acquireWorker(ctx context.Context) bool {
+	if w.active == nil {
+		return true
+	}
+
+	select {
+	case w.active <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-w.closing:
+		return false
+	}
+}
+
+// releaseWorker releases a worker reserved with acquireWorker.
+func (w *workers[R]) releaseWorker() {
+	if w.active != nil {
+		<-w.active
+	}
+}
+
+// dispatchAfter dispatches the task after the delay, unless workers are stopped or closed first.
+func (w *workers[R]) dispatchAfter(ctx context.Context, t task[R], delay time.Duration) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		if !w.acquireWorker(ctx) {
+			w.wg.Done()
+			return
+		}
+
+		w.inFlight.Add(1)
+		w.dispatch(ctx, t)
+
+	case <-ctx.Done():
+		w.wg.Done()
+
+	case <-w.closing:
+		w.wg.Done()
+	}
+}
+
+func (w *workers[R]) heartbeat(ctx context.Context) {
+	ticks := w.config.HeartbeatTicks
+
+	var ticker *time.Ticker
+	if ticks == nil {
+		if w.config.HeartbeatInterval <= 0 {
+			return
+		}
+
+		ticker = time.NewTicker(w.config.HeartbeatInterval)
+		ticks = ticker.C
+	}
+
+	go func() {
+		if ticker != nil {
+			defer ticker.Stop()
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-ticks:
+				w.config.OnHeartbeat(w.Stats())
+			}
+		}
+	}()
+}
+
+func (w *workers[R]) prewarm() {
+	if w.config.Prewarm == 0 {
+		return
+	}
+
+	n := w.config.Prewarm
+	if w.config.MaxWorkers > 0 && n > w.config.MaxWorkers {
+		n = w.config.MaxWorkers
+	}
+
+	pool.Prewarm(w.pool, n)
+}
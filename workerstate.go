@@ -0,0 +1,51 @@
+package workers
+
+import "context"
+
+type workerStateKey struct{}
+
+// WorkerState returns the value created by WorkerInit for the worker executing the task with ctx.
+func WorkerState(ctx context.Context) interface{} {
+	return ctx.Value(workerStateKey{})
+}
+
+// init creates the worker state with WorkerInit, if set, unless it has already been created.
+func (w *worker[R]) init() error {
+	if w.owner.config.WorkerInit == nil {
+		return nil
+	}
+
+	w.initMu.Lock()
+	defer w.initMu.Unlock()
+
+	if w.initialized {
+		return nil
+	}
+
+	state, err := w.owner.config.WorkerInit()
+	if err != nil {
+		return err
+	}
+	w.state, w.initialized = state, true
+
+	w.owner.statesMu.Lock()
+	w.owner.states = append(w.owner.states, state)
+	w.owner.statesMu.Unlock()
+
+	return nil
+}
+
+// shutdownWorkers calls WorkerShutdown for the states of all initialized workers.
+func (w *workers[R]) shutdownWorkers() {
+	if w.config.WorkerShutdown == nil {
+		return
+	}
+
+	w.statesMu.Lock()
+	defer w.statesMu.Unlock()
+
+	for _, state := range w.states {
+		w.config.WorkerShutdown(state)
+	}
+	w.states = nil
+}